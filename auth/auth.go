@@ -10,8 +10,9 @@ import (
 // VerifyToken verifies the authenticity of a JWT token using the provided secret key.
 //
 // This function parses the provided `tokenString`, verifies its signature using the provided
-// `secretKey`, and checks the token's validity. If the token is invalid or the verification
-// fails, an error is returned.
+// `secretKey`, and checks the token's validity. Only tokens signed with HS256, HS384 or HS512 are accepted.
+// If the token is invalid or the verification fails, an error wrapping ErrTokenExpired, ErrSignatureInvalid,
+// ErrAlgorithmNotAllowed or ErrTokenInvalid is returned. Use NewVerifier for other algorithms.
 //
 // EXAMPLE USAGE:
 //
@@ -27,19 +28,11 @@ import (
 //   - token: the JWT token that can be verified and used for authorization purposes
 //   - error: An ERROR if the token cannot be parsed or is invalid; nil otherwise.
 func VerifyToken(tokenString, secretKey string) (*jwt.Token, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secretKey), nil
-	})
-
+	verifier, err := NewVerifier(secretKey, HMACAlgorithms...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse token: %s", err.Error())
-	}
-
-	if !token.Valid {
-		return nil, fmt.Errorf("invalid token")
+		return nil, err
 	}
-
-	return token, nil
+	return verifier.Verify(tokenString)
 }
 
 // ExtractValueFromTokenClaim extracts a specified value from the claims in a JWT token.
@@ -71,6 +64,8 @@ func VerifyToken(tokenString, secretKey string) (*jwt.Token, error) {
 // Errors:
 //   - Returns an error if the claims cannot be cast to `jwt.MapClaims`.
 //   - Returns an error if the specified `valueField` is missing or not a string.
+//
+// Use ClaimString, ClaimStrings, ClaimInt64 and ClaimBool for other types and nested claims, or ParseClaims to decode all claims into a struct.
 
 func ExtractValueFromTokenClaim(token *jwt.Token, valueField string) (string, error) {
 	// Extract value from given field from token claims
@@ -2,13 +2,67 @@
 package auth
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
 func TestVerifyToken(t *testing.T) {
+	expiredClaims := validClaims()
+	expiredClaims["exp"] = time.Now().Add(-time.Minute).Unix()
 
+	tests := []struct {
+		name        string
+		token       string
+		secret      string
+		expectedErr error
+	}{
+		{
+			name:   "Valid token",
+			token:  signToken(t, jwt.SigningMethodHS256, []byte(testSecret), validClaims()),
+			secret: testSecret,
+		},
+		{
+			name:   "Valid token signed with HS512",
+			token:  signToken(t, jwt.SigningMethodHS512, []byte(testSecret), validClaims()),
+			secret: testSecret,
+		},
+		{
+			name:        "Expired token",
+			token:       signToken(t, jwt.SigningMethodHS256, []byte(testSecret), expiredClaims),
+			secret:      testSecret,
+			expectedErr: ErrTokenExpired,
+		},
+		{
+			name:        "Forged token",
+			token:       signToken(t, jwt.SigningMethodHS256, []byte("forged"), validClaims()),
+			secret:      testSecret,
+			expectedErr: ErrSignatureInvalid,
+		},
+		{
+			name:        "Unsigned token",
+			token:       signToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, validClaims()),
+			secret:      testSecret,
+			expectedErr: ErrAlgorithmNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := VerifyToken(tt.token, tt.secret)
+			if tt.expectedErr == nil {
+				if err != nil || token == nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("expected error wrapping %v, got: %v", tt.expectedErr, err)
+			}
+		})
+	}
 }
 
 func TestExtractValueFromTokenClaim(t *testing.T) {
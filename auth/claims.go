@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrClaimType is returned when a claim exists but does not hold a value of the requested type
+var ErrClaimType = errors.New("token claim has an unexpected type")
+
+// ParseClaims decodes the claims of token into a new T, following the json tags of T.
+// T is usually a struct embedding jwt.RegisteredClaims next to the custom claims of the identity provider.
+//
+// Example usage:
+//
+//	type SupabaseClaims struct {
+//		jwt.RegisteredClaims
+//		SessionID   string `json:"session_id"`
+//		Role        string `json:"role"`
+//		AppMetadata struct {
+//			TenantID int64    `json:"tenant_id"`
+//			Roles    []string `json:"roles"`
+//		} `json:"app_metadata"`
+//	}
+//
+//	claims, err := auth.ParseClaims[SupabaseClaims](token)
+//	if err != nil {
+//		return err
+//	}
+//	userID := claims.Subject
+func ParseClaims[T any](token *jwt.Token) (*T, error) {
+	if token == nil || token.Claims == nil {
+		return nil, fmt.Errorf("failed to parse token claims: %w", ErrTokenInvalid)
+	}
+	if claims, ok := interface{}(token.Claims).(*T); ok {
+		return claims, nil
+	}
+
+	data, err := json.Marshal(token.Claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode token claims: %s", err.Error())
+	}
+	claims := new(T)
+	if err := json.Unmarshal(data, claims); err != nil {
+		return nil, fmt.Errorf("failed to parse token claims: %w: %s", ErrClaimType, err.Error())
+	}
+	return claims, nil
+}
+
+// ClaimString returns the string claim at path. A path is a claim name, or claim names separated by dots
+// to reach into nested objects, e.g. "app_metadata.role". A claim whose name contains dots,
+// e.g. the namespaced "https://example.com/roles" of Auth0, is found by its full name first.
+// The returned error wraps ErrClaimMissing if there is no claim at path and ErrClaimType if it is not a string.
+func ClaimString(token *jwt.Token, path string) (string, error) {
+	value, err := claimValue(token, path)
+	if err != nil {
+		return "", err
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%w: %q is %T, not a string", ErrClaimType, path, value)
+	}
+	return s, nil
+}
+
+// ClaimStrings returns the string array claim at path, e.g. "app_metadata.roles".
+// A single string is returned as an array of one element, as the "aud" claim may be either.
+func ClaimStrings(token *jwt.Token, path string) ([]string, error) {
+	value, err := claimValue(token, path)
+	if err != nil {
+		return nil, err
+	}
+	switch v := value.(type) {
+	case string:
+		return []string{v}, nil
+	case []string:
+		return v, nil
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, element := range v {
+			s, ok := element.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: %q contains %T, not a string", ErrClaimType, path, element)
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("%w: %q is %T, not a string array", ErrClaimType, path, value)
+	}
+}
+
+// maxSafeInteger is the largest integer a float64 claim holds exactly: larger ones may have been rounded when decoded
+const maxSafeInteger = 1<<53 - 1
+
+// ClaimInt64 returns the integer claim at path, e.g. "app_metadata.tenant_id".
+// Numbers with a fraction are rejected with ErrClaimType. Claims are decoded from JSON as float64,
+// so integers beyond ±(2^53-1) are rejected as well, as they may have been rounded to another integer.
+func ClaimInt64(token *jwt.Token, path string) (int64, error) {
+	value, err := claimValue(token, path)
+	if err != nil {
+		return 0, err
+	}
+	switch v := value.(type) {
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, fmt.Errorf("%w: %q is %v, not an integer", ErrClaimType, path, v)
+		}
+		if math.Abs(v) > maxSafeInteger {
+			return 0, fmt.Errorf("%w: %q is %v, too large to be read exactly", ErrClaimType, path, v)
+		}
+		return int64(v), nil
+	case json.Number:
+		i, err := v.Int64()
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q is %s, not an integer", ErrClaimType, path, v)
+		}
+		return i, nil
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	default:
+		return 0, fmt.Errorf("%w: %q is %T, not an integer", ErrClaimType, path, value)
+	}
+}
+
+// ClaimBool returns the boolean claim at path, e.g. "email_verified"
+func ClaimBool(token *jwt.Token, path string) (bool, error) {
+	value, err := claimValue(token, path)
+	if err != nil {
+		return false, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("%w: %q is %T, not a boolean", ErrClaimType, path, value)
+	}
+	return b, nil
+}
+
+// claimValue returns the top-level claim named path if there is one, else walks the dot separated path through the map claims of token
+func claimValue(token *jwt.Token, path string) (interface{}, error) {
+	if token == nil {
+		return nil, fmt.Errorf("failed to read token claims: %w", ErrTokenInvalid)
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, fmt.Errorf("failed to read token claims: %w: claims are %T, not jwt.MapClaims", ErrTokenInvalid, token.Claims)
+	}
+
+	if value, ok := claims[path]; ok && value != nil {
+		return value, nil
+	}
+	var value interface{} = map[string]interface{}(claims)
+	for _, name := range strings.Split(path, ".") {
+		var object map[string]interface{}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			object = v
+		case jwt.MapClaims:
+			object = v
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrClaimMissing, path)
+		}
+		value, ok = object[name]
+		if !ok || value == nil {
+			return nil, fmt.Errorf("%w: %q", ErrClaimMissing, path)
+		}
+	}
+	return value, nil
+}
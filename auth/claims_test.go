@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type testClaims struct {
+	jwt.RegisteredClaims
+	SessionID   string `json:"session_id"`
+	AppMetadata struct {
+		Role     string   `json:"role"`
+		TenantID int64    `json:"tenant_id"`
+		Roles    []string `json:"roles"`
+	} `json:"app_metadata"`
+}
+
+// parsedTestToken signs and verifies a token, so its claims hold the types decoded from JSON
+func parsedTestToken(t *testing.T) *jwt.Token {
+	t.Helper()
+	claims := validClaims()
+	claims["session_id"] = "session-1"
+	claims["aud"] = []string{"authenticated", "prices-api"}
+	claims["email_verified"] = true
+	claims["ratio"] = 0.5
+	claims["safe_id"] = int64(1<<53 - 1)
+	claims["unsafe_id"] = int64(1<<53 + 1)
+	claims["https://example.com/roles"] = []string{"viewer"}
+	claims["app_metadata"] = map[string]interface{}{
+		"role":      "admin",
+		"tenant_id": 42,
+		"roles":     []string{"admin", "editor"},
+		"mixed":     []interface{}{"admin", 1},
+	}
+	token, err := VerifyToken(signToken(t, jwt.SigningMethodHS256, []byte(testSecret), claims), testSecret)
+	if err != nil {
+		t.Fatalf("failed to verify token: %v", err)
+	}
+	return token
+}
+
+func TestParseClaims(t *testing.T) {
+	claims, err := ParseClaims[testClaims](parsedTestToken(t))
+	if err != nil {
+		t.Fatalf("failed to parse claims: %v", err)
+	}
+	if claims.Subject != "user-1" || claims.SessionID != "session-1" || claims.ExpiresAt == nil {
+		t.Errorf("Expected registered and custom claims, got %+v", claims)
+	}
+	if !reflect.DeepEqual(claims.Audience, jwt.ClaimStrings{"authenticated", "prices-api"}) {
+		t.Errorf("Expected audience, got %v", claims.Audience)
+	}
+	if claims.AppMetadata.Role != "admin" || claims.AppMetadata.TenantID != 42 || !reflect.DeepEqual(claims.AppMetadata.Roles, []string{"admin", "editor"}) {
+		t.Errorf("Expected nested claims, got %+v", claims.AppMetadata)
+	}
+
+	mismatch := &jwt.Token{Claims: jwt.MapClaims{"session_id": 1}}
+	if _, err := ParseClaims[testClaims](mismatch); !errors.Is(err, ErrClaimType) {
+		t.Errorf("Expected ErrClaimType, got %v", err)
+	}
+
+	typed := &testClaims{SessionID: "session-2"}
+	if claims, err := ParseClaims[testClaims](&jwt.Token{Claims: typed}); err != nil || claims != typed {
+		t.Errorf("Expected the claims of the token, got %+v, %v", claims, err)
+	}
+}
+
+func TestClaimAccessors(t *testing.T) {
+	token := parsedTestToken(t)
+
+	tests := []struct {
+		name        string
+		get         func() (interface{}, error)
+		expected    interface{}
+		expectedErr error
+	}{
+		{name: "String", get: func() (interface{}, error) { return ClaimString(token, "sub") }, expected: "user-1"},
+		{name: "Nested string", get: func() (interface{}, error) { return ClaimString(token, "app_metadata.role") }, expected: "admin"},
+		{name: "Strings", get: func() (interface{}, error) { return ClaimStrings(token, "aud") }, expected: []string{"authenticated", "prices-api"}},
+		{name: "Single string as strings", get: func() (interface{}, error) { return ClaimStrings(token, "sub") }, expected: []string{"user-1"}},
+		{name: "Nested strings", get: func() (interface{}, error) { return ClaimStrings(token, "app_metadata.roles") }, expected: []string{"admin", "editor"}},
+		{name: "Int64", get: func() (interface{}, error) { return ClaimInt64(token, "app_metadata.tenant_id") }, expected: int64(42)},
+		{name: "Bool", get: func() (interface{}, error) { return ClaimBool(token, "email_verified") }, expected: true},
+		{
+			name:     "Namespaced claim with dots",
+			get:      func() (interface{}, error) { return ClaimStrings(token, "https://example.com/roles") },
+			expected: []string{"viewer"},
+		},
+		{
+			name:        "Missing claim",
+			get:         func() (interface{}, error) { return ClaimString(token, "role") },
+			expectedErr: ErrClaimMissing,
+		},
+		{
+			name:        "Missing nested claim",
+			get:         func() (interface{}, error) { return ClaimString(token, "app_metadata.plan") },
+			expectedErr: ErrClaimMissing,
+		},
+		{
+			name:        "Path through a string",
+			get:         func() (interface{}, error) { return ClaimString(token, "sub.id") },
+			expectedErr: ErrClaimMissing,
+		},
+		{
+			name:        "String of another type",
+			get:         func() (interface{}, error) { return ClaimString(token, "email_verified") },
+			expectedErr: ErrClaimType,
+		},
+		{
+			name:        "Strings containing another type",
+			get:         func() (interface{}, error) { return ClaimStrings(token, "app_metadata.mixed") },
+			expectedErr: ErrClaimType,
+		},
+		{
+			name:        "Int64 with a fraction",
+			get:         func() (interface{}, error) { return ClaimInt64(token, "ratio") },
+			expectedErr: ErrClaimType,
+		},
+		{name: "Largest exact int64", get: func() (interface{}, error) { return ClaimInt64(token, "safe_id") }, expected: int64(1<<53 - 1)},
+		{
+			name:        "Int64 rounded when decoded",
+			get:         func() (interface{}, error) { return ClaimInt64(token, "unsafe_id") },
+			expectedErr: ErrClaimType,
+		},
+		{
+			name:        "Int64 of another type",
+			get:         func() (interface{}, error) { return ClaimInt64(token, "sub") },
+			expectedErr: ErrClaimType,
+		},
+		{
+			name:        "Bool of another type",
+			get:         func() (interface{}, error) { return ClaimBool(token, "app_metadata") },
+			expectedErr: ErrClaimType,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, err := test.get()
+			if test.expectedErr != nil {
+				if !errors.Is(err, test.expectedErr) {
+					t.Errorf("Expected error wrapping %v, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(value, test.expected) {
+				t.Errorf("Expected %v, got %v", test.expected, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/cache"
+	"github.com/AnhCaooo/go-goods/clock"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+// ErrRefreshTokenInvalid is returned when a refresh token is unknown, expired, revoked or was already used
+var ErrRefreshTokenInvalid = errors.New("refresh token is invalid")
+
+// refreshJanitorInterval is how often the default refresh token store purges tokens which expired without being used
+var refreshJanitorInterval = 10 * time.Minute
+
+// refreshTokenPrefix namespaces refresh tokens in a cache which may be shared with other entries
+const refreshTokenPrefix = "refresh-token:"
+
+// RefreshStore keeps the sessions of refresh tokens until they are used, revoked or expire.
+// It is implemented by cache.Cache, which keeps them in the process, and by cache.RedisStore,
+// which shares them between replicas and keeps them across restarts.
+type RefreshStore interface {
+	// SetExpiredAfterTimePeriod stores the value for the given duration
+	SetExpiredAfterTimePeriod(key string, value interface{}, duration time.Duration)
+	// Take atomically returns and removes the value of key, so a refresh token can be used only once
+	Take(key string) (interface{}, bool)
+	// Delete removes the value of key
+	Delete(key string)
+	// RegisterType registers the type of sample under name, so stored sessions can be read back
+	RegisterType(name string, sample interface{})
+}
+
+var (
+	_ RefreshStore = (*cache.Cache)(nil)
+	_ RefreshStore = (*cache.RedisStore)(nil)
+)
+
+// IssuerConfig configures how an Issuer signs access tokens and how long its tokens are valid.
+type IssuerConfig struct {
+	Key           interface{}   // []byte or string for HMAC, *rsa.PrivateKey, *ecdsa.PrivateKey or ed25519.PrivateKey
+	Algorithm     string        // signing algorithm, default is HS256, RS256, ES256/ES384/ES512 by curve or EdDSA depending on Key
+	KeyID         string        // "kid" header, letting verifiers select the key from a JWKS document
+	Issuer        string        // "iss" claim
+	Audience      []string      // "aud" claim
+	TTL           time.Duration // lifetime of access tokens, default is 15 minutes
+	RefreshTTL    time.Duration // lifetime of refresh tokens, default is 30 days
+	RefreshTokens RefreshStore  // store of refresh tokens, default is a new in-memory cache.Cache, see Issuer
+	Clock         clock.Clock   // default is the system clock
+}
+
+// Issuer signs access tokens and issues opaque refresh tokens, which are exchanged for a new token pair with Refresh.
+// Refresh tokens are random strings: only their SHA-256 hash is stored, together with the subject and claims of the access token.
+//
+// By default refresh tokens are kept in a private in-memory cache: they are lost when the process restarts and can only be
+// refreshed by the replica which issued them. Services running more than one replica set IssuerConfig.RefreshTokens
+// to a shared store such as cache.RedisStore.
+// The default cache purges expired refresh tokens in the background until Close is called.
+type Issuer struct {
+	config       IssuerConfig
+	method       jwt.SigningMethod
+	defaultStore *cache.Cache // nil when the refresh tokens are kept in IssuerConfig.RefreshTokens
+}
+
+// TokenPair is an access token together with the refresh token to renew it
+type TokenPair struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"` // expiration of the access token
+}
+
+// refreshSessionType is the name refreshSession is registered under in the TypeCodec of a RefreshStore
+const refreshSessionType = "auth.refreshSession"
+
+// refreshSession is what a refresh token grants: a new access token for subject with claims
+type refreshSession struct {
+	Subject string
+	Claims  map[string]interface{}
+}
+
+// NewIssuer returns an Issuer, returning an error if the key does not suit the algorithm.
+// "none" is never accepted, as it suits no key.
+//
+// Example usage:
+//
+//	issuer, err := auth.NewIssuer(logger, auth.IssuerConfig{
+//		Key:      privateKey,
+//		KeyID:    "2026-10",
+//		Issuer:   "https://prices.example.com",
+//		Audience: []string{"notifications-api"},
+//		TTL:      5 * time.Minute,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer issuer.Close()
+//	accessToken, err := issuer.IssueAccessToken("prices-service", map[string]interface{}{"scope": "notifications:send"})
+func NewIssuer(logger *zap.Logger, config IssuerConfig) (*Issuer, error) {
+	if secret, ok := config.Key.(string); ok {
+		config.Key = []byte(secret)
+	}
+	if secret, ok := config.Key.([]byte); ok && len(secret) == 0 {
+		return nil, fmt.Errorf("failed to create token issuer: empty secret")
+	}
+	if config.Algorithm == "" {
+		config.Algorithm = defaultAlgorithm(config.Key)
+	}
+	if !signingKeySuitsAlgorithm(config.Key, config.Algorithm) {
+		return nil, fmt.Errorf("failed to create token issuer: %w: %s cannot be used with a key of type %T", ErrAlgorithmNotAllowed, config.Algorithm, config.Key)
+	}
+	if config.TTL <= 0 {
+		config.TTL = 15 * time.Minute
+	}
+	if config.RefreshTTL <= 0 {
+		config.RefreshTTL = 30 * 24 * time.Hour
+	}
+	if config.Clock == nil {
+		config.Clock = clock.Real{}
+	}
+	var defaultStore *cache.Cache
+	if config.RefreshTokens == nil {
+		// refresh tokens which are never used would otherwise stay in memory forever
+		defaultStore = cache.NewCache(logger,
+			cache.WithClock(config.Clock),
+			cache.WithJanitor(context.Background(), refreshJanitorInterval),
+		)
+		config.RefreshTokens = defaultStore
+	}
+	config.RefreshTokens.RegisterType(refreshSessionType, refreshSession{})
+
+	return &Issuer{
+		config:       config,
+		method:       jwt.GetSigningMethod(config.Algorithm),
+		defaultStore: defaultStore,
+	}, nil
+}
+
+// Close stops purging expired refresh tokens from the default in-memory store.
+// A store set in IssuerConfig.RefreshTokens is left open, as it belongs to the caller. It is safe to call Close more than once.
+func (i *Issuer) Close() {
+	if i.defaultStore != nil {
+		i.defaultStore.Close()
+	}
+}
+
+// IssueAccessToken signs an access token for subject, valid for the configured TTL.
+// claims are added to the token, but cannot replace the registered claims "iss", "sub", "aud", "iat", "nbf", "exp" and "jti".
+func (i *Issuer) IssueAccessToken(subject string, claims map[string]interface{}) (string, error) {
+	token, _, err := i.signAccessToken(subject, claims)
+	return token, err
+}
+
+// IssueTokens signs an access token for subject and issues a refresh token which renews it with the same claims.
+//
+// Example usage:
+//
+//	tokens, err := issuer.IssueTokens(userID, map[string]interface{}{"session_id": sessionID})
+//	if err != nil {
+//		return err
+//	}
+//	encode.EncodeResponse(w, http.StatusOK, tokens)
+func (i *Issuer) IssueTokens(subject string, claims map[string]interface{}) (TokenPair, error) {
+	accessToken, expiresAt, err := i.signAccessToken(subject, claims)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refreshToken, err := randomToken()
+	if err != nil {
+		return TokenPair{}, err
+	}
+	session := refreshSession{Subject: subject, Claims: maps.Clone(claims)}
+	i.config.RefreshTokens.SetExpiredAfterTimePeriod(refreshTokenKey(refreshToken), session, i.config.RefreshTTL)
+
+	return TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+// Refresh exchanges refreshToken for a new token pair. The refresh token is rotated: it can be used only once,
+// so a second use, e.g. by someone who stole it, fails with an error wrapping ErrRefreshTokenInvalid.
+func (i *Issuer) Refresh(refreshToken string) (TokenPair, error) {
+	// only one of concurrent refreshes with the same token can take its session
+	value, found := i.config.RefreshTokens.Take(refreshTokenKey(refreshToken))
+	session, ok := value.(refreshSession)
+	if !found || !ok {
+		return TokenPair{}, fmt.Errorf("failed to refresh token: %w", ErrRefreshTokenInvalid)
+	}
+	return i.IssueTokens(session.Subject, session.Claims)
+}
+
+// RevokeRefreshToken makes refreshToken unusable, e.g. when the user signs out
+func (i *Issuer) RevokeRefreshToken(refreshToken string) {
+	i.config.RefreshTokens.Delete(refreshTokenKey(refreshToken))
+}
+
+// signAccessToken signs an access token for subject and returns it with its expiration
+func (i *Issuer) signAccessToken(subject string, claims map[string]interface{}) (string, time.Time, error) {
+	id, err := randomToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	now := i.config.Clock.Now()
+	expiresAt := now.Add(i.config.TTL)
+
+	tokenClaims := jwt.MapClaims{}
+	maps.Copy(tokenClaims, claims)
+	delete(tokenClaims, "iss")
+	delete(tokenClaims, "aud")
+	tokenClaims["sub"] = subject
+	tokenClaims["iat"] = now.Unix()
+	tokenClaims["nbf"] = now.Unix()
+	tokenClaims["exp"] = expiresAt.Unix()
+	tokenClaims["jti"] = id
+	if i.config.Issuer != "" {
+		tokenClaims["iss"] = i.config.Issuer
+	}
+	if len(i.config.Audience) > 0 {
+		tokenClaims["aud"] = jwt.ClaimStrings(i.config.Audience)
+	}
+
+	token := jwt.NewWithClaims(i.method, tokenClaims)
+	if i.config.KeyID != "" {
+		token.Header["kid"] = i.config.KeyID
+	}
+	signed, err := token.SignedString(i.config.Key)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to sign token: %s", err.Error())
+	}
+	return signed, expiresAt, nil
+}
+
+// randomToken returns 32 random bytes encoded as base64url
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random token: %s", err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// refreshTokenKey returns the cache key of a refresh token, so the token itself is never stored
+func refreshTokenKey(refreshToken string) string {
+	hash := sha256.Sum256([]byte(refreshToken))
+	return refreshTokenPrefix + hex.EncodeToString(hash[:])
+}
+
+// defaultAlgorithm returns the algorithm an Issuer uses for key when none is configured
+func defaultAlgorithm(key interface{}) string {
+	switch k := key.(type) {
+	case []byte:
+		return jwt.SigningMethodHS256.Alg()
+	case *rsa.PrivateKey:
+		return jwt.SigningMethodRS256.Alg()
+	case *ecdsa.PrivateKey:
+		switch k.Curve.Params().BitSize {
+		case 384:
+			return jwt.SigningMethodES384.Alg()
+		case 521:
+			return jwt.SigningMethodES512.Alg()
+		default:
+			return jwt.SigningMethodES256.Alg()
+		}
+	case ed25519.PrivateKey:
+		return jwt.SigningMethodEdDSA.Alg()
+	default:
+		return ""
+	}
+}
+
+// signingKeySuitsAlgorithm reports whether key has the type the algorithm signs with
+func signingKeySuitsAlgorithm(key interface{}, algorithm string) bool {
+	switch method := jwt.GetSigningMethod(algorithm).(type) {
+	case *jwt.SigningMethodHMAC:
+		_, ok := key.([]byte)
+		return ok
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		_, ok := key.(*rsa.PrivateKey)
+		return ok
+	case *jwt.SigningMethodECDSA:
+		k, ok := key.(*ecdsa.PrivateKey)
+		return ok && k.Curve.Params().BitSize == method.CurveBits
+	case *jwt.SigningMethodEd25519:
+		k, ok := key.(ed25519.PrivateKey)
+		return ok && len(k) == ed25519.PrivateKeySize
+	default:
+		return false
+	}
+}
@@ -0,0 +1,356 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/cache"
+	"github.com/AnhCaooo/go-goods/clock"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+func TestNewIssuer(t *testing.T) {
+	keys := newTestKeys(t)
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ECDSA key: %v", err)
+	}
+
+	tests := []struct {
+		name              string
+		config            IssuerConfig
+		expectedAlgorithm string
+		expectedErr       error
+	}{
+		{name: "Secret", config: IssuerConfig{Key: testSecret}, expectedAlgorithm: "HS256"},
+		{name: "Secret with HS512", config: IssuerConfig{Key: []byte(testSecret), Algorithm: "HS512"}, expectedAlgorithm: "HS512"},
+		{name: "RSA key", config: IssuerConfig{Key: keys.rsa}, expectedAlgorithm: "RS256"},
+		{name: "RSA key with PS256", config: IssuerConfig{Key: keys.rsa, Algorithm: "PS256"}, expectedAlgorithm: "PS256"},
+		{name: "P-256 key", config: IssuerConfig{Key: keys.ec}, expectedAlgorithm: "ES256"},
+		{name: "P-384 key", config: IssuerConfig{Key: p384Key}, expectedAlgorithm: "ES384"},
+		{name: "Ed25519 key", config: IssuerConfig{Key: keys.ed25519}, expectedAlgorithm: "EdDSA"},
+		{name: "Empty secret", config: IssuerConfig{Key: ""}, expectedErr: errors.New("any")},
+		{name: "No key", config: IssuerConfig{}, expectedErr: ErrAlgorithmNotAllowed},
+		{name: "Public key", config: IssuerConfig{Key: &keys.rsa.PublicKey}, expectedErr: ErrAlgorithmNotAllowed},
+		{name: "Algorithm none", config: IssuerConfig{Key: testSecret, Algorithm: "none"}, expectedErr: ErrAlgorithmNotAllowed},
+		{name: "RSA algorithm with secret", config: IssuerConfig{Key: testSecret, Algorithm: "RS256"}, expectedErr: ErrAlgorithmNotAllowed},
+		{name: "Curve not matching algorithm", config: IssuerConfig{Key: p384Key, Algorithm: "ES256"}, expectedErr: ErrAlgorithmNotAllowed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			issuer, err := NewIssuer(zap.NewNop(), test.config)
+			if test.expectedErr == nil {
+				if err != nil {
+					t.Fatalf("Expected an issuer, got error %v", err)
+				}
+				if alg := issuer.method.Alg(); alg != test.expectedAlgorithm {
+					t.Errorf("Expected algorithm %s, got %s", test.expectedAlgorithm, alg)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Expected an error, got nil")
+			}
+			if errors.Is(test.expectedErr, ErrAlgorithmNotAllowed) && !errors.Is(err, ErrAlgorithmNotAllowed) {
+				t.Errorf("Expected error wrapping %v, got %v", ErrAlgorithmNotAllowed, err)
+			}
+		})
+	}
+}
+
+func TestIssueAccessTokenRoundTrip(t *testing.T) {
+	issuer, err := NewIssuer(zap.NewNop(), IssuerConfig{
+		Key:      testSecret,
+		Issuer:   "https://auth.example.com",
+		Audience: []string{"prices-api"},
+		TTL:      5 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("failed to create issuer: %v", err)
+	}
+	tokenString, err := issuer.IssueAccessToken("user-1", map[string]interface{}{
+		"session_id": "session-1",
+		"roles":      []string{"admin"},
+		"iss":        "https://evil.example.com",
+	})
+	if err != nil {
+		t.Fatalf("failed to issue token: %v", err)
+	}
+
+	token, err := VerifyToken(tokenString, testSecret)
+	if err != nil {
+		t.Fatalf("Expected the issued token to verify, got %v", err)
+	}
+	if sessionID, _ := ClaimString(token, "session_id"); sessionID != "session-1" {
+		t.Errorf("Expected custom claim session_id, got %q", sessionID)
+	}
+	if roles, _ := ClaimStrings(token, "roles"); len(roles) != 1 || roles[0] != "admin" {
+		t.Errorf("Expected custom claim roles, got %v", roles)
+	}
+	claims, err := ParseClaims[jwt.RegisteredClaims](token)
+	if err != nil {
+		t.Fatalf("failed to parse claims: %v", err)
+	}
+	if claims.Subject != "user-1" || claims.Issuer != "https://auth.example.com" || claims.ID == "" {
+		t.Errorf("Expected registered claims, got %+v", claims)
+	}
+	if ttl := claims.ExpiresAt.Sub(claims.IssuedAt.Time); ttl != 5*time.Minute {
+		t.Errorf("Expected a TTL of 5m, got %v", ttl)
+	}
+
+	verifier, err := NewVerifierWithOptions(VerifierOptions{
+		Secret:         testSecret,
+		Issuers:        []string{"https://auth.example.com"},
+		Audience:       "prices-api",
+		RequiredClaims: []string{"session_id"},
+		MaxAge:         time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	if _, err := verifier.Verify(tokenString); err != nil {
+		t.Errorf("Expected the issued token to pass the claim rules, got %v", err)
+	}
+	if _, err := VerifyToken(tokenString, "other-secret"); !errors.Is(err, ErrSignatureInvalid) {
+		t.Errorf("Expected ErrSignatureInvalid with another secret, got %v", err)
+	}
+}
+
+func TestIssueAccessTokenAsymmetric(t *testing.T) {
+	keys := newTestKeys(t)
+	server := newJWKSServer(t, rsaJWK("rsa", keys.rsa, "RS256"), ecJWK("ec", keys.ec), ed25519JWK("ed", keys.ed25519))
+	keySet, err := NewKeySet(zap.NewNop(), KeySetConfig{URL: server.URL})
+	if err != nil {
+		t.Fatalf("failed to create key set: %v", err)
+	}
+	verifier, err := NewKeySetVerifier(keySet, "RS256", "ES256", "EdDSA")
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		key         interface{}
+		kid         string
+		expectedErr error
+	}{
+		{name: "RSA key", key: keys.rsa, kid: "rsa"},
+		{name: "ECDSA key", key: keys.ec, kid: "ec"},
+		{name: "Ed25519 key", key: keys.ed25519, kid: "ed"},
+		{name: "Key ID of another key", key: keys.ec, kid: "ed", expectedErr: ErrAlgorithmNotAllowed},
+		{name: "Unknown key ID", key: keys.rsa, kid: "unknown", expectedErr: ErrUnknownKey},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			issuer, err := NewIssuer(zap.NewNop(), IssuerConfig{Key: test.key, KeyID: test.kid})
+			if err != nil {
+				t.Fatalf("failed to create issuer: %v", err)
+			}
+			tokenString, err := issuer.IssueAccessToken("service-1", nil)
+			if err != nil {
+				t.Fatalf("failed to issue token: %v", err)
+			}
+			_, err = verifier.Verify(tokenString)
+			if test.expectedErr == nil {
+				if err != nil {
+					t.Errorf("Expected the issued token to verify, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, test.expectedErr) {
+				t.Errorf("Expected error wrapping %v, got %v", test.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestIssuerRefresh(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	issuer, err := NewIssuer(zap.NewNop(), IssuerConfig{
+		Key:        testSecret,
+		TTL:        time.Minute,
+		RefreshTTL: time.Hour,
+		Clock:      clk,
+	})
+	if err != nil {
+		t.Fatalf("failed to create issuer: %v", err)
+	}
+	tokens, err := issuer.IssueTokens("user-1", map[string]interface{}{"session_id": "session-1"})
+	if err != nil {
+		t.Fatalf("failed to issue tokens: %v", err)
+	}
+	if tokens.RefreshToken == "" || tokens.RefreshToken == tokens.AccessToken {
+		t.Fatalf("Expected an opaque refresh token, got %q", tokens.RefreshToken)
+	}
+	if _, err := jwt.Parse(tokens.RefreshToken, nil); err == nil {
+		t.Error("Expected the refresh token not to be a JWT")
+	}
+
+	clk.Advance(30 * time.Minute)
+	refreshed, err := issuer.Refresh(tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("failed to refresh: %v", err)
+	}
+	if refreshed.RefreshToken == tokens.RefreshToken {
+		t.Error("Expected the refresh token to be rotated")
+	}
+	if !refreshed.ExpiresAt.Equal(clk.Now().Add(time.Minute)) {
+		t.Errorf("Expected the new access token to expire at %v, got %v", clk.Now().Add(time.Minute), refreshed.ExpiresAt)
+	}
+	verifier, err := NewVerifierWithOptions(VerifierOptions{Secret: testSecret, Clock: clk})
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	verified, err := verifier.Verify(refreshed.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected the refreshed access token to verify, got %v", err)
+	}
+	if sessionID, _ := ClaimString(verified, "session_id"); sessionID != "session-1" {
+		t.Errorf("Expected the claims to be kept on refresh, got session_id %q", sessionID)
+	}
+
+	if _, err := issuer.Refresh(tokens.RefreshToken); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("Expected a used refresh token to be rejected, got %v", err)
+	}
+	if _, err := issuer.Refresh("unknown"); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("Expected an unknown refresh token to be rejected, got %v", err)
+	}
+
+	issuer.RevokeRefreshToken(refreshed.RefreshToken)
+	if _, err := issuer.Refresh(refreshed.RefreshToken); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("Expected a revoked refresh token to be rejected, got %v", err)
+	}
+
+	expiring, err := issuer.IssueTokens("user-1", nil)
+	if err != nil {
+		t.Fatalf("failed to issue tokens: %v", err)
+	}
+	clk.Advance(2 * time.Hour)
+	if _, err := issuer.Refresh(expiring.RefreshToken); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("Expected an expired refresh token to be rejected, got %v", err)
+	}
+}
+
+func TestIssuerPurgesUnusedRefreshTokens(t *testing.T) {
+	interval := refreshJanitorInterval
+	refreshJanitorInterval = time.Millisecond
+	t.Cleanup(func() { refreshJanitorInterval = interval })
+
+	clk := clock.NewFake(time.Now())
+	issuer, err := NewIssuer(zap.NewNop(), IssuerConfig{Key: testSecret, RefreshTTL: time.Hour, Clock: clk})
+	if err != nil {
+		t.Fatalf("failed to create issuer: %v", err)
+	}
+	defer issuer.Close()
+	if _, err := issuer.IssueTokens("user-1", nil); err != nil {
+		t.Fatalf("failed to issue tokens: %v", err)
+	}
+
+	clk.Advance(2 * time.Hour)
+	deadline := time.Now().Add(2 * time.Second)
+	for issuer.defaultStore.Stats().Expirations != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the unused refresh token to be purged")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestIssuerCloseKeepsConfiguredStore(t *testing.T) {
+	store := cache.NewCache(zap.NewNop())
+	issuer, err := NewIssuer(zap.NewNop(), IssuerConfig{Key: testSecret, RefreshTokens: store})
+	if err != nil {
+		t.Fatalf("failed to create issuer: %v", err)
+	}
+	issuer.Close()
+	issuer.Close()
+
+	tokens, err := issuer.IssueTokens("user-1", nil)
+	if err != nil {
+		t.Fatalf("failed to issue tokens: %v", err)
+	}
+	if _, err := issuer.Refresh(tokens.RefreshToken); err != nil {
+		t.Errorf("Expected the configured store to stay usable after Close, got %v", err)
+	}
+}
+
+func TestIssuerRefreshConcurrent(t *testing.T) {
+	issuer, err := NewIssuer(zap.NewNop(), IssuerConfig{Key: testSecret})
+	if err != nil {
+		t.Fatalf("failed to create issuer: %v", err)
+	}
+	tokens, err := issuer.IssueTokens("user-1", nil)
+	if err != nil {
+		t.Fatalf("failed to issue tokens: %v", err)
+	}
+
+	var succeeded atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := issuer.Refresh(tokens.RefreshToken); err == nil {
+				succeeded.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := succeeded.Load(); n != 1 {
+		t.Errorf("Expected exactly one refresh to succeed, got %d", n)
+	}
+}
+
+func TestIssuerSharedRefreshStore(t *testing.T) {
+	store := cache.NewCache(zap.NewNop())
+	first, err := NewIssuer(zap.NewNop(), IssuerConfig{Key: testSecret, RefreshTokens: store})
+	if err != nil {
+		t.Fatalf("failed to create issuer: %v", err)
+	}
+	second, err := NewIssuer(zap.NewNop(), IssuerConfig{Key: testSecret, RefreshTokens: store})
+	if err != nil {
+		t.Fatalf("failed to create issuer: %v", err)
+	}
+	tokens, err := first.IssueTokens("user-1", map[string]interface{}{"session_id": "session-1"})
+	if err != nil {
+		t.Fatalf("failed to issue tokens: %v", err)
+	}
+
+	// the sessions are registered with the store, so they survive a snapshot
+	var snapshot bytes.Buffer
+	if err := store.Snapshot(&snapshot); err != nil {
+		t.Fatalf("failed to snapshot refresh tokens: %v", err)
+	}
+	restored := cache.NewCache(zap.NewNop())
+	restarted, err := NewIssuer(zap.NewNop(), IssuerConfig{Key: testSecret, RefreshTokens: restored})
+	if err != nil {
+		t.Fatalf("failed to create issuer: %v", err)
+	}
+	if err := restored.Restore(&snapshot); err != nil {
+		t.Fatalf("failed to restore refresh tokens: %v", err)
+	}
+	if _, err := restarted.Refresh(tokens.RefreshToken); err != nil {
+		t.Errorf("Expected a restored refresh token to be usable, got %v", err)
+	}
+
+	refreshed, err := second.Refresh(tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("Expected a refresh token issued by another issuer to be usable, got %v", err)
+	}
+	if _, err := first.Refresh(tokens.RefreshToken); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("Expected a used refresh token to be rejected by every issuer, got %v", err)
+	}
+	if _, err := first.Refresh(refreshed.RefreshToken); err != nil {
+		t.Errorf("Expected the rotated refresh token to be usable, got %v", err)
+	}
+}
@@ -0,0 +1,351 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/cache"
+	"github.com/AnhCaooo/go-goods/clock"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+// ErrUnknownKey is returned when a token refers to a key ID which is not in the key set, even after refreshing it
+var ErrUnknownKey = errors.New("token key is unknown")
+
+// KeySetConfig configures where a KeySet loads its JWKS document from and how long it keeps the keys.
+// Exactly one of URL and File must be set.
+type KeySetConfig struct {
+	URL                string        // URL of the JWKS document, e.g. "https://auth.example.com/.well-known/jwks.json"
+	File               string        // path of a JWKS document on disk
+	Client             *http.Client  // client used to fetch URL, default is a client with a 10 seconds timeout
+	CacheTTL           time.Duration // how long loaded keys are used before the document is loaded again, default is one hour. Keys are kept if loading fails
+	MinRefreshInterval time.Duration // minimum time between two loads triggered by unknown key IDs, default is one minute
+	Clock              clock.Clock   // default is the system clock
+}
+
+// KeySet holds the public keys of a JWKS document, selected by their key ID ("kid").
+// Keys are cached in a cache.Cache. A token signed with a key ID which is not cached yet, e.g. after the identity provider
+// rotated its keys, makes the KeySet load the document again, at most once per MinRefreshInterval.
+// The keys of the last successful load stay in use until a later load succeeds, so an unavailable JWKS endpoint
+// does not make valid tokens fail.
+type KeySet struct {
+	logger      *zap.Logger
+	config      KeySetConfig
+	keys        *cache.Cache
+	refreshLock sync.Mutex
+	lastRefresh time.Time    // guarded by refreshLock
+	loadedAt    atomic.Int64 // Unix nanoseconds of the last successful load
+}
+
+// maxJWKSSize is the size in bytes of the largest JWKS document a KeySet reads from a URL.
+// Documents are loaded while token verifications wait, so an oversized response fails instead of being read to the end.
+const maxJWKSSize = 1 << 20
+
+// keyRetention is how long keys stay in the cache: they are only replaced or removed by a successful load
+const keyRetention = 100 * 365 * 24 * time.Hour
+
+// jwk is a key of a JWKS document (RFC 7517). Only the fields of RSA, EC and OKP (Ed25519) public keys are read.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey is a parsed key of the key set together with the algorithm it is restricted to, if the document names one
+type publicKey struct {
+	key       interface{}
+	algorithm string
+}
+
+// NewKeySet returns a KeySet and loads its JWKS document, returning an error if the document cannot be loaded.
+//
+// Example usage:
+//
+//	keys, err := auth.NewKeySet(logger, auth.KeySetConfig{URL: "https://auth.example.com/.well-known/jwks.json"})
+//	if err != nil {
+//		return err
+//	}
+//	verifier, err := auth.NewKeySetVerifier(keys, "RS256", "ES256")
+//	if err != nil {
+//		return err
+//	}
+//	token, err := verifier.Verify(tokenString)
+func NewKeySet(logger *zap.Logger, config KeySetConfig) (*KeySet, error) {
+	if (config.URL == "") == (config.File == "") {
+		return nil, fmt.Errorf("failed to create key set: exactly one of URL and File must be set")
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if config.CacheTTL <= 0 {
+		config.CacheTTL = time.Hour
+	}
+	if config.MinRefreshInterval <= 0 {
+		config.MinRefreshInterval = time.Minute
+	}
+	if config.Clock == nil {
+		config.Clock = clock.Real{}
+	}
+
+	s := &KeySet{
+		logger: logger,
+		config: config,
+		keys:   cache.NewCache(logger, cache.WithClock(config.Clock)),
+	}
+	if err := s.Refresh(context.Background()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Key returns the public key with the given key ID. If it is not cached, the JWKS document is loaded again,
+// unless it was loaded less than MinRefreshInterval ago, in which case an error wrapping ErrUnknownKey is returned.
+func (s *KeySet) Key(kid string) (interface{}, error) {
+	key, err := s.lookup(kid)
+	if err != nil {
+		return nil, err
+	}
+	return key.key, nil
+}
+
+func (s *KeySet) lookup(kid string) (publicKey, error) {
+	if key, found := s.keys.Get(kid); found && !s.stale() {
+		return key.(publicKey), nil
+	}
+
+	s.refreshLock.Lock()
+	defer s.refreshLock.Unlock()
+	// another caller may have loaded the key while this one waited for the lock
+	key, found := s.keys.Get(kid)
+	if found && !s.stale() {
+		return key.(publicKey), nil
+	}
+	if s.config.Clock.Now().Sub(s.lastRefresh) >= s.config.MinRefreshInterval {
+		if err := s.refresh(context.Background()); err != nil {
+			if !found {
+				return publicKey{}, err
+			}
+			s.logger.Warn("[go-goods] failed to refresh JWKS, using the keys loaded before", zap.Error(err))
+		} else {
+			key, found = s.keys.Get(kid)
+		}
+	}
+	if found {
+		return key.(publicKey), nil
+	}
+	return publicKey{}, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+}
+
+// stale reports whether the keys were loaded more than CacheTTL ago
+func (s *KeySet) stale() bool {
+	return s.config.Clock.Now().Sub(time.Unix(0, s.loadedAt.Load())) >= s.config.CacheTTL
+}
+
+// Refresh loads the JWKS document and replaces the cached keys with its keys. If loading fails, the cached keys are kept.
+func (s *KeySet) Refresh(ctx context.Context) error {
+	s.refreshLock.Lock()
+	defer s.refreshLock.Unlock()
+	return s.refresh(ctx)
+}
+
+// refresh loads the JWKS document. The caller must hold refreshLock.
+func (s *KeySet) refresh(ctx context.Context) error {
+	s.lastRefresh = s.config.Clock.Now()
+
+	document, err := s.load(ctx)
+	if err != nil {
+		return err
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(document, &set); err != nil {
+		return fmt.Errorf("failed to decode JWKS: %s", err.Error())
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			s.logger.Warn("[go-goods] skipped JWKS key", zap.String("kid", k.Kid), zap.Error(err))
+			continue
+		}
+		keys[k.Kid] = publicKey{key: key, algorithm: k.Alg}
+	}
+
+	var removed []string
+	for kid := range s.keys.Keys() {
+		if _, found := keys[kid]; !found {
+			removed = append(removed, kid)
+		}
+	}
+	s.keys.DeleteMany(removed...)
+	s.keys.SetMany(keys, keyRetention)
+	s.loadedAt.Store(s.config.Clock.Now().UnixNano())
+	s.logger.Debug("[go-goods] JWKS loaded", zap.Int("keys", len(keys)))
+	return nil
+}
+
+// load reads the JWKS document from the configured file or URL
+func (s *KeySet) load(ctx context.Context) ([]byte, error) {
+	if s.config.File != "" {
+		document, err := os.ReadFile(s.config.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWKS file: %s", err.Error())
+		}
+		return document, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.config.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWKS request: %s", err.Error())
+	}
+	resp, err := s.config.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %s", err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: unexpected status %d", resp.StatusCode)
+	}
+	document, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS response: %s", err.Error())
+	}
+	if len(document) > maxJWKSSize {
+		return nil, fmt.Errorf("failed to read JWKS response: document is larger than %d bytes", maxJWKSSize)
+	}
+	return document, nil
+}
+
+// NewKeySetVerifier returns a Verifier which checks signatures with the key of keys named by the "kid" header of the token,
+// and only accepts the given asymmetric algorithms. A key restricted to an algorithm by the JWKS document only verifies tokens of that algorithm.
+func NewKeySetVerifier(keys *KeySet, algorithms ...string) (*Verifier, error) {
+	keyFunc, err := keySetKeyFunc(keys, algorithms)
+	if err != nil {
+		return nil, err
+	}
+	return newVerifier(algorithms, keyFunc, VerifierOptions{}), nil
+}
+
+// keySetKeyFunc returns a jwt.Keyfunc which selects the key of a token from keys by its "kid" header
+func keySetKeyFunc(keys *KeySet, algorithms []string) (jwt.Keyfunc, error) {
+	if err := checkAlgorithms(algorithms); err != nil {
+		return nil, err
+	}
+	for _, algorithm := range algorithms {
+		if _, ok := jwt.GetSigningMethod(algorithm).(*jwt.SigningMethodHMAC); ok {
+			return nil, fmt.Errorf("failed to create token verifier: %w: %s cannot be used with a key set", ErrAlgorithmNotAllowed, algorithm)
+		}
+	}
+
+	return func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, fmt.Errorf("%w: token has no kid", ErrUnknownKey)
+		}
+		key, err := keys.lookup(kid)
+		if err != nil {
+			return nil, err
+		}
+		algorithm := token.Method.Alg()
+		if (key.algorithm != "" && key.algorithm != algorithm) || !keySuitsAlgorithm(key.key, algorithm) {
+			return nil, fmt.Errorf("%w: key %q cannot verify %s", ErrAlgorithmNotAllowed, kid, algorithm)
+		}
+		return key.key, nil
+	}, nil
+}
+
+// publicKey parses the public key of the JWK
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if x.BitLen() > size*8 || y.BitLen() > size*8 {
+			return nil, fmt.Errorf("invalid EC public key: coordinates are longer than %d bytes", size)
+		}
+		point := append([]byte{4}, x.FillBytes(make([]byte, size))...)
+		point = append(point, y.FillBytes(make([]byte, size))...)
+		key, err := ecdsa.ParseUncompressedPublicKey(curve, point)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC public key: %s", err.Error())
+		}
+		return key, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 public key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// decodeBigInt decodes a base64url encoded big-endian integer
+func decodeBigInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("invalid key parameter %q", value)
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,420 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/clock"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+// testKeys are the private keys whose public halves are served by a jwksServer
+type testKeys struct {
+	rsa     *rsa.PrivateKey
+	ec      *ecdsa.PrivateKey
+	ed25519 ed25519.PrivateKey
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ECDSA key: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate Ed25519 key: %v", err)
+	}
+	return testKeys{rsa: rsaKey, ec: ecKey, ed25519: edKey}
+}
+
+func encodeBigInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func rsaJWK(kid string, key *rsa.PrivateKey, alg string) jwk {
+	return jwk{Kty: "RSA", Kid: kid, Use: "sig", Alg: alg, N: encodeBigInt(key.N), E: encodeBigInt(big.NewInt(int64(key.E)))}
+}
+
+func ecJWK(kid string, key *ecdsa.PrivateKey) jwk {
+	return jwk{Kty: "EC", Kid: kid, Crv: "P-256", X: encodeBigInt(key.X), Y: encodeBigInt(key.Y)}
+}
+
+func ed25519JWK(kid string, key ed25519.PrivateKey) jwk {
+	return jwk{Kty: "OKP", Kid: kid, Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(key.Public().(ed25519.PublicKey))}
+}
+
+// jwksServer serves a JWKS document which can be replaced or made to fail while it runs, and counts how often it was fetched
+type jwksServer struct {
+	*httptest.Server
+	lock     sync.Mutex
+	keys     []jwk
+	requests atomic.Int32
+	failing  atomic.Bool
+}
+
+func newJWKSServer(t *testing.T, keys ...jwk) *jwksServer {
+	t.Helper()
+	s := &jwksServer{keys: keys}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		if s.failing.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		json.NewEncoder(w).Encode(map[string][]jwk{"keys": s.keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) setKeys(keys ...jwk) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.keys = keys
+}
+
+func signWithKid(t *testing.T, method jwt.SigningMethod, key interface{}, kid string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, validClaims())
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signed
+}
+
+func TestKeySetVerifier(t *testing.T) {
+	keys := newTestKeys(t)
+	other := newTestKeys(t)
+	server := newJWKSServer(t,
+		rsaJWK("rsa", keys.rsa, "RS256"),
+		ecJWK("ec", keys.ec),
+		ed25519JWK("ed", keys.ed25519),
+		jwk{Kty: "RSA", Kid: "encryption", Use: "enc", N: encodeBigInt(keys.rsa.N), E: "AQAB"},
+	)
+	keySet, err := NewKeySet(zap.NewNop(), KeySetConfig{URL: server.URL})
+	if err != nil {
+		t.Fatalf("failed to create key set: %v", err)
+	}
+	verifier, err := NewKeySetVerifier(keySet, "RS256", "PS256", "ES256", "EdDSA")
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		token       string
+		expectedErr error
+	}{
+		{name: "RS256 token", token: signWithKid(t, jwt.SigningMethodRS256, keys.rsa, "rsa")},
+		{name: "ES256 token", token: signWithKid(t, jwt.SigningMethodES256, keys.ec, "ec")},
+		{name: "EdDSA token", token: signWithKid(t, jwt.SigningMethodEdDSA, keys.ed25519, "ed")},
+		{
+			name:        "Token signed with another key",
+			token:       signWithKid(t, jwt.SigningMethodRS256, other.rsa, "rsa"),
+			expectedErr: ErrSignatureInvalid,
+		},
+		{
+			name:        "Token using another algorithm than the key",
+			token:       signWithKid(t, jwt.SigningMethodPS256, keys.rsa, "rsa"),
+			expectedErr: ErrAlgorithmNotAllowed,
+		},
+		{
+			name:        "Token naming a key of another type",
+			token:       signWithKid(t, jwt.SigningMethodES256, keys.ec, "rsa"),
+			expectedErr: ErrAlgorithmNotAllowed,
+		},
+		{
+			name:        "HMAC token",
+			token:       signWithKid(t, jwt.SigningMethodHS256, []byte(testSecret), "rsa"),
+			expectedErr: ErrAlgorithmNotAllowed,
+		},
+		{
+			name:        "Token without kid",
+			token:       signWithKid(t, jwt.SigningMethodRS256, keys.rsa, ""),
+			expectedErr: ErrUnknownKey,
+		},
+		{
+			name:        "Encryption key",
+			token:       signWithKid(t, jwt.SigningMethodRS256, keys.rsa, "encryption"),
+			expectedErr: ErrUnknownKey,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			token, err := verifier.Verify(test.token)
+			if test.expectedErr == nil {
+				if err != nil || !token.Valid {
+					t.Errorf("Expected a valid token, got error %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, test.expectedErr) {
+				t.Errorf("Expected error wrapping %v, got %v", test.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestKeySetRefresh(t *testing.T) {
+	keys := newTestKeys(t)
+	rotated := newTestKeys(t)
+	server := newJWKSServer(t, rsaJWK("old", keys.rsa, ""))
+	clk := clock.NewFake(time.Now())
+	keySet, err := NewKeySet(zap.NewNop(), KeySetConfig{
+		URL:                server.URL,
+		CacheTTL:           time.Hour,
+		MinRefreshInterval: time.Minute,
+		Clock:              clk,
+	})
+	if err != nil {
+		t.Fatalf("failed to create key set: %v", err)
+	}
+	clk.Advance(2 * time.Minute)
+
+	// the identity provider rotates its key: the unknown kid triggers one refresh
+	server.setKeys(rsaJWK("new", rotated.rsa, ""))
+	if _, err := keySet.Key("new"); err != nil {
+		t.Fatalf("Expected the rotated key after a refresh, got %v", err)
+	}
+	if n := server.requests.Load(); n != 2 {
+		t.Errorf("Expected 2 requests, got %d", n)
+	}
+	if _, err := keySet.Key("old"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected the rotated out key to be removed, got %v", err)
+	}
+
+	// unknown kids do not refresh again within the minimum interval
+	for i := 0; i < 5; i++ {
+		if _, err := keySet.Key("unknown"); !errors.Is(err, ErrUnknownKey) {
+			t.Errorf("Expected ErrUnknownKey, got %v", err)
+		}
+	}
+	if n := server.requests.Load(); n != 2 {
+		t.Errorf("Expected refreshes to be rate limited to 2 requests, got %d", n)
+	}
+
+	clk.Advance(time.Minute)
+	keySet.Key("unknown")
+	if n := server.requests.Load(); n != 3 {
+		t.Errorf("Expected a refresh after the minimum interval, got %d requests", n)
+	}
+
+	// cached keys expire after the cache TTL and are loaded again
+	clk.Advance(2 * time.Hour)
+	if _, err := keySet.Key("new"); err != nil {
+		t.Errorf("Expected the key to be loaded again, got %v", err)
+	}
+	if n := server.requests.Load(); n != 4 {
+		t.Errorf("Expected a refresh after the cache TTL, got %d requests", n)
+	}
+}
+
+func TestKeySetFile(t *testing.T) {
+	keys := newTestKeys(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	document, _ := json.Marshal(map[string][]jwk{"keys": {ed25519JWK("ed", keys.ed25519)}})
+	if err := os.WriteFile(path, document, 0o600); err != nil {
+		t.Fatalf("failed to write JWKS file: %v", err)
+	}
+
+	keySet, err := NewKeySet(zap.NewNop(), KeySetConfig{File: path})
+	if err != nil {
+		t.Fatalf("failed to create key set: %v", err)
+	}
+	key, err := keySet.Key("ed")
+	if err != nil {
+		t.Fatalf("Expected key, got %v", err)
+	}
+	if !keys.ed25519.Public().(ed25519.PublicKey).Equal(key) {
+		t.Error("Expected the public key of the file")
+	}
+}
+
+func TestNewKeySetErrors(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	invalid := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not json"))
+	}))
+	defer invalid.Close()
+	document, _ := json.Marshal(map[string][]jwk{"keys": {rsaJWK("key-1", newTestKeys(t).rsa, "RS256")}})
+	oversized := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a valid document, only too large
+		w.Write(append(bytes.Repeat([]byte(" "), maxJWKSSize), document...))
+	}))
+	defer oversized.Close()
+
+	tests := []struct {
+		name   string
+		config KeySetConfig
+	}{
+		{name: "Neither URL nor file", config: KeySetConfig{}},
+		{name: "Both URL and file", config: KeySetConfig{URL: invalid.URL, File: "jwks.json"}},
+		{name: "Server error", config: KeySetConfig{URL: failing.URL}},
+		{name: "Invalid document", config: KeySetConfig{URL: invalid.URL}},
+		{name: "Document too large", config: KeySetConfig{URL: oversized.URL}},
+		{name: "Missing file", config: KeySetConfig{File: filepath.Join(t.TempDir(), "missing.json")}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewKeySet(zap.NewNop(), test.config); err == nil {
+				t.Error("Expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestNewKeySetVerifierRejectsHMAC(t *testing.T) {
+	keys := newTestKeys(t)
+	server := newJWKSServer(t, rsaJWK("rsa", keys.rsa, ""))
+	keySet, err := NewKeySet(zap.NewNop(), KeySetConfig{URL: server.URL})
+	if err != nil {
+		t.Fatalf("failed to create key set: %v", err)
+	}
+
+	if _, err := NewKeySetVerifier(keySet, "RS256", "HS256"); !errors.Is(err, ErrAlgorithmNotAllowed) {
+		t.Errorf("Expected ErrAlgorithmNotAllowed, got %v", err)
+	}
+}
+
+func TestJWKPublicKey(t *testing.T) {
+	keys := newTestKeys(t)
+	valid := ecJWK("ec", keys.ec)
+	tooLong := encodeBigInt(new(big.Int).Lsh(big.NewInt(1), 256)) // 33 bytes, longer than a P-256 coordinate
+
+	tests := []struct {
+		name        string
+		key         jwk
+		expectedErr bool
+	}{
+		{name: "RSA key", key: rsaJWK("rsa", keys.rsa, "")},
+		{name: "EC key", key: valid},
+		{name: "Ed25519 key", key: ed25519JWK("ed", keys.ed25519)},
+		{name: "EC x longer than the curve size", key: jwk{Kty: "EC", Crv: "P-256", X: tooLong, Y: valid.Y}, expectedErr: true},
+		{name: "EC y longer than the curve size", key: jwk{Kty: "EC", Crv: "P-256", X: valid.X, Y: tooLong}, expectedErr: true},
+		{name: "EC point not on the curve", key: jwk{Kty: "EC", Crv: "P-256", X: valid.X, Y: valid.X}, expectedErr: true},
+		{name: "Unsupported curve", key: jwk{Kty: "EC", Crv: "P-192", X: valid.X, Y: valid.Y}, expectedErr: true},
+		{name: "Ed25519 key of wrong length", key: jwk{Kty: "OKP", Crv: "Ed25519", X: "AQID"}, expectedErr: true},
+		{name: "Unsupported key type", key: jwk{Kty: "oct"}, expectedErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key, err := test.key.publicKey()
+			if test.expectedErr {
+				if err == nil {
+					t.Errorf("Expected an error, got key %v", key)
+				}
+				return
+			}
+			if err != nil || key == nil {
+				t.Errorf("Expected a key, got error %v", err)
+			}
+		})
+	}
+}
+
+func TestKeySetSkipsInvalidKeys(t *testing.T) {
+	keys := newTestKeys(t)
+	valid := ecJWK("ec", keys.ec)
+	invalid := jwk{Kty: "EC", Kid: "invalid", Crv: "P-256", X: encodeBigInt(new(big.Int).Lsh(big.NewInt(1), 256)), Y: valid.Y}
+	server := newJWKSServer(t, invalid, valid)
+
+	keySet, err := NewKeySet(zap.NewNop(), KeySetConfig{URL: server.URL})
+	if err != nil {
+		t.Fatalf("failed to create key set: %v", err)
+	}
+	if _, err := keySet.Key("ec"); err != nil {
+		t.Errorf("Expected the valid key, got %v", err)
+	}
+	if _, err := keySet.Key("invalid"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected the invalid key to be skipped, got %v", err)
+	}
+}
+
+func TestKeySetKeepsKeysWhenRefreshFails(t *testing.T) {
+	keys := newTestKeys(t)
+	server := newJWKSServer(t, rsaJWK("rsa", keys.rsa, ""))
+	clk := clock.NewFake(time.Now())
+	keySet, err := NewKeySet(zap.NewNop(), KeySetConfig{
+		URL:                server.URL,
+		CacheTTL:           time.Hour,
+		MinRefreshInterval: time.Minute,
+		Clock:              clk,
+	})
+	if err != nil {
+		t.Fatalf("failed to create key set: %v", err)
+	}
+	verifier, err := NewKeySetVerifier(keySet, "RS256")
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	token := signWithKid(t, jwt.SigningMethodRS256, keys.rsa, "rsa")
+
+	// the JWKS endpoint goes down while the keys are due for a refresh
+	server.failing.Store(true)
+	clk.Advance(2 * time.Hour)
+	if _, err := verifier.Verify(token); err != nil {
+		t.Errorf("Expected the keys loaded before to be used, got %v", err)
+	}
+	if n := server.requests.Load(); n != 2 {
+		t.Errorf("Expected one failed refresh, got %d requests", n)
+	}
+	if _, err := verifier.Verify(token); err != nil {
+		t.Errorf("Expected the keys loaded before to be used, got %v", err)
+	}
+	if n := server.requests.Load(); n != 2 {
+		t.Errorf("Expected failed refreshes to be rate limited, got %d requests", n)
+	}
+	if err := keySet.Refresh(context.Background()); err == nil {
+		t.Error("Expected Refresh to report the failure")
+	}
+	if _, err := keySet.Key("rsa"); err != nil {
+		t.Errorf("Expected the key to be kept after a failed Refresh, got %v", err)
+	}
+
+	// once the endpoint is back, the next refresh replaces the keys
+	rotated := newTestKeys(t)
+	server.setKeys(rsaJWK("rotated", rotated.rsa, ""))
+	server.failing.Store(false)
+	clk.Advance(time.Minute)
+	if _, err := verifier.Verify(signWithKid(t, jwt.SigningMethodRS256, rotated.rsa, "rotated")); err != nil {
+		t.Errorf("Expected the rotated key after the endpoint recovered, got %v", err)
+	}
+	if _, err := keySet.Key("rsa"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected the rotated out key to be removed, got %v", err)
+	}
+}
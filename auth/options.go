@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/clock"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrIssuerInvalid    = errors.New("token issuer is not accepted")   // the "iss" claim is not one of the expected issuers
+	ErrAudienceInvalid  = errors.New("token audience is not accepted") // the "aud" claim does not contain the expected audience
+	ErrClaimMissing     = errors.New("token claim is missing")         // a required claim is not in the token, including "iss", "aud" and "iat" when their rule is set
+	ErrTokenTooOld      = errors.New("token is too old")               // the token was issued ("iat") longer than MaxAge ago
+	ErrTokenNotValidYet = errors.New("token is not valid yet")         // the token is before its "nbf" claim or was issued in the future
+)
+
+// AsymmetricAlgorithms are the algorithms accepted by NewVerifierWithOptions when a KeySet is set and no algorithm is given
+var AsymmetricAlgorithms = []string{
+	jwt.SigningMethodRS256.Alg(),
+	jwt.SigningMethodRS384.Alg(),
+	jwt.SigningMethodRS512.Alg(),
+	jwt.SigningMethodPS256.Alg(),
+	jwt.SigningMethodPS384.Alg(),
+	jwt.SigningMethodPS512.Alg(),
+	jwt.SigningMethodES256.Alg(),
+	jwt.SigningMethodES384.Alg(),
+	jwt.SigningMethodES512.Alg(),
+	jwt.SigningMethodEdDSA.Alg(),
+}
+
+// VerifierOptions configures the key of a Verifier and the claims it validates besides the signature and the expiration.
+// Exactly one of Secret and KeySet must be set. Rules which are left empty are not checked.
+type VerifierOptions struct {
+	Secret         string        // shared HMAC secret
+	KeySet         *KeySet       // public keys selected by the "kid" header of the token
+	Algorithms     []string      // allowed algorithms, default is HMACAlgorithms with Secret and AsymmetricAlgorithms with KeySet
+	Issuers        []string      // accepted values of the "iss" claim
+	Audience       string        // value the "aud" claim must contain
+	Leeway         time.Duration // clock skew tolerated when checking "exp", "nbf", "iat" and MaxAge
+	RequiredClaims []string      // names of claims the token must contain, e.g. "sub" or "session_id"
+	MaxAge         time.Duration // maximum time since the "iat" claim, which becomes required
+	Clock          clock.Clock   // default is the system clock
+}
+
+// NewVerifierWithOptions returns a Verifier which checks the signature and expiration of tokens, then the claim rules of options.
+// Each failed rule is reported by its own error: ErrIssuerInvalid, ErrAudienceInvalid, ErrClaimMissing, ErrTokenTooOld or ErrTokenNotValidYet.
+//
+// Example usage:
+//
+//	verifier, err := auth.NewVerifierWithOptions(auth.VerifierOptions{
+//		Secret:         jwtSecret,
+//		Issuers:        []string{"https://auth.example.com"},
+//		Audience:       "prices-api",
+//		Leeway:         30 * time.Second,
+//		RequiredClaims: []string{"sub", "session_id"},
+//		MaxAge:         24 * time.Hour,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	token, err := verifier.Verify(tokenString)
+//	if errors.Is(err, auth.ErrAudienceInvalid) {
+//		// the token was issued for another service
+//	}
+func NewVerifierWithOptions(options VerifierOptions) (*Verifier, error) {
+	if (options.Secret == "") == (options.KeySet == nil) {
+		return nil, fmt.Errorf("failed to create token verifier: exactly one of Secret and KeySet must be set")
+	}
+	if options.Leeway < 0 || options.MaxAge < 0 {
+		return nil, fmt.Errorf("failed to create token verifier: Leeway and MaxAge must not be negative")
+	}
+
+	algorithms := options.Algorithms
+	var keyFunc jwt.Keyfunc
+	var err error
+	if options.KeySet != nil {
+		if len(algorithms) == 0 {
+			algorithms = AsymmetricAlgorithms
+		}
+		keyFunc, err = keySetKeyFunc(options.KeySet, algorithms)
+	} else {
+		if len(algorithms) == 0 {
+			algorithms = HMACAlgorithms
+		}
+		keyFunc, err = staticKeyFunc(options.Secret, algorithms)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newVerifier(algorithms, keyFunc, options), nil
+}
+
+// validateClaims checks the claim rules which the jwt parser does not: the issuer, the required claims and the maximum age
+func (v *Verifier) validateClaims(token *jwt.Token) error {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return fmt.Errorf("failed to validate token claims: %w", ErrTokenInvalid)
+	}
+
+	if len(v.issuers) > 0 {
+		if _, found := claims["iss"]; !found {
+			return fmt.Errorf("failed to validate token claims: %w: %q", ErrClaimMissing, "iss")
+		}
+		issuer, _ := claims["iss"].(string)
+		if !slices.Contains(v.issuers, issuer) {
+			return fmt.Errorf("failed to validate token claims: %w: %q", ErrIssuerInvalid, issuer)
+		}
+	}
+
+	for _, name := range v.requiredClaims {
+		if value, found := claims[name]; !found || value == nil {
+			return fmt.Errorf("failed to validate token claims: %w: %q", ErrClaimMissing, name)
+		}
+	}
+
+	if v.maxAge > 0 {
+		issuedAt, err := claims.GetIssuedAt()
+		if err != nil {
+			return fmt.Errorf("failed to validate token claims: %w: %s", ErrTokenInvalid, err.Error())
+		}
+		if issuedAt == nil {
+			return fmt.Errorf("failed to validate token claims: %w: %q", ErrClaimMissing, "iat")
+		}
+		if age := v.clock.Now().Sub(issuedAt.Time); age > v.maxAge+v.leeway {
+			return fmt.Errorf("failed to validate token claims: %w: issued %s ago", ErrTokenTooOld, age.Round(time.Second))
+		}
+	}
+	return nil
+}
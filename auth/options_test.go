@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/clock"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+func TestNewVerifierWithOptions(t *testing.T) {
+	keys := newTestKeys(t)
+	server := newJWKSServer(t, rsaJWK("rsa", keys.rsa, ""))
+	keySet, err := NewKeySet(zap.NewNop(), KeySetConfig{URL: server.URL})
+	if err != nil {
+		t.Fatalf("failed to create key set: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		options     VerifierOptions
+		expectedErr error
+	}{
+		{name: "Secret", options: VerifierOptions{Secret: testSecret}},
+		{name: "Key set", options: VerifierOptions{KeySet: keySet}},
+		{name: "Neither secret nor key set", options: VerifierOptions{}, expectedErr: errors.New("any")},
+		{name: "Both secret and key set", options: VerifierOptions{Secret: testSecret, KeySet: keySet}, expectedErr: errors.New("any")},
+		{name: "Negative leeway", options: VerifierOptions{Secret: testSecret, Leeway: -time.Second}, expectedErr: errors.New("any")},
+		{name: "Asymmetric algorithm with secret", options: VerifierOptions{Secret: testSecret, Algorithms: []string{"RS256"}}, expectedErr: ErrAlgorithmNotAllowed},
+		{name: "HMAC algorithm with key set", options: VerifierOptions{KeySet: keySet, Algorithms: []string{"HS256"}}, expectedErr: ErrAlgorithmNotAllowed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			verifier, err := NewVerifierWithOptions(test.options)
+			if test.expectedErr == nil {
+				if err != nil || verifier == nil {
+					t.Errorf("Expected a verifier, got error %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Expected an error, got nil")
+			}
+			if errors.Is(test.expectedErr, ErrAlgorithmNotAllowed) && !errors.Is(err, ErrAlgorithmNotAllowed) {
+				t.Errorf("Expected error wrapping %v, got %v", ErrAlgorithmNotAllowed, err)
+			}
+		})
+	}
+}
+
+func TestVerifierOptionsRules(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	options := VerifierOptions{
+		Secret:         testSecret,
+		Issuers:        []string{"https://auth.example.com", "https://legacy.example.com"},
+		Audience:       "prices-api",
+		Leeway:         30 * time.Second,
+		RequiredClaims: []string{"sub", "session_id"},
+		MaxAge:         time.Hour,
+		Clock:          clock.NewFake(now),
+	}
+	claims := func(change func(jwt.MapClaims)) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"sub":        "user-1",
+			"session_id": "session-1",
+			"iss":        "https://auth.example.com",
+			"aud":        []string{"prices-api", "other-api"},
+			"iat":        now.Add(-time.Minute).Unix(),
+			"exp":        now.Add(time.Hour).Unix(),
+		}
+		if change != nil {
+			change(c)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name        string
+		claims      jwt.MapClaims
+		expectedErr error
+	}{
+		{name: "Valid token", claims: claims(nil)},
+		{name: "Other accepted issuer", claims: claims(func(c jwt.MapClaims) { c["iss"] = "https://legacy.example.com" })},
+		{name: "Audience as string", claims: claims(func(c jwt.MapClaims) { c["aud"] = "prices-api" })},
+		{name: "Expired within leeway", claims: claims(func(c jwt.MapClaims) { c["exp"] = now.Add(-10 * time.Second).Unix() })},
+		{name: "Not before within leeway", claims: claims(func(c jwt.MapClaims) { c["nbf"] = now.Add(10 * time.Second).Unix() })},
+		{name: "Issued at the max age within leeway", claims: claims(func(c jwt.MapClaims) { c["iat"] = now.Add(-time.Hour - 10*time.Second).Unix() })},
+		{
+			name:        "Expired beyond leeway",
+			claims:      claims(func(c jwt.MapClaims) { c["exp"] = now.Add(-time.Minute).Unix() }),
+			expectedErr: ErrTokenExpired,
+		},
+		{
+			name:        "Not before beyond leeway",
+			claims:      claims(func(c jwt.MapClaims) { c["nbf"] = now.Add(time.Minute).Unix() }),
+			expectedErr: ErrTokenNotValidYet,
+		},
+		{
+			name:        "Issued in the future",
+			claims:      claims(func(c jwt.MapClaims) { c["iat"] = now.Add(time.Minute).Unix() }),
+			expectedErr: ErrTokenNotValidYet,
+		},
+		{
+			name:        "Unknown issuer",
+			claims:      claims(func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }),
+			expectedErr: ErrIssuerInvalid,
+		},
+		{
+			name:        "Missing issuer",
+			claims:      claims(func(c jwt.MapClaims) { delete(c, "iss") }),
+			expectedErr: ErrClaimMissing,
+		},
+		{
+			name:        "Other audience",
+			claims:      claims(func(c jwt.MapClaims) { c["aud"] = "other-api" }),
+			expectedErr: ErrAudienceInvalid,
+		},
+		{
+			name:        "Missing audience",
+			claims:      claims(func(c jwt.MapClaims) { delete(c, "aud") }),
+			expectedErr: ErrClaimMissing,
+		},
+		{
+			name:        "Missing required claim",
+			claims:      claims(func(c jwt.MapClaims) { delete(c, "session_id") }),
+			expectedErr: ErrClaimMissing,
+		},
+		{
+			name:        "Missing issued at",
+			claims:      claims(func(c jwt.MapClaims) { delete(c, "iat") }),
+			expectedErr: ErrClaimMissing,
+		},
+		{
+			name:        "Older than max age",
+			claims:      claims(func(c jwt.MapClaims) { c["iat"] = now.Add(-2 * time.Hour).Unix() }),
+			expectedErr: ErrTokenTooOld,
+		},
+	}
+
+	verifier, err := NewVerifierWithOptions(options)
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			token, err := verifier.Verify(signToken(t, jwt.SigningMethodHS256, []byte(testSecret), test.claims))
+			if test.expectedErr == nil {
+				if err != nil || token == nil || !token.Valid {
+					t.Errorf("Expected a valid token, got error %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, test.expectedErr) {
+				t.Errorf("Expected error wrapping %v, got %v", test.expectedErr, err)
+			}
+			if token != nil {
+				t.Error("Expected no token on error")
+			}
+		})
+	}
+}
+
+func TestVerifierOptionsKeySet(t *testing.T) {
+	keys := newTestKeys(t)
+	server := newJWKSServer(t, ecJWK("ec", keys.ec))
+	keySet, err := NewKeySet(zap.NewNop(), KeySetConfig{URL: server.URL})
+	if err != nil {
+		t.Fatalf("failed to create key set: %v", err)
+	}
+	verifier, err := NewVerifierWithOptions(VerifierOptions{KeySet: keySet, Audience: "prices-api"})
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+
+	claims := validClaims()
+	claims["aud"] = "prices-api"
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = "ec"
+	signed, err := token.SignedString(keys.ec)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	if _, err := verifier.Verify(signed); err != nil {
+		t.Errorf("Expected a valid token, got error %v", err)
+	}
+
+	if _, err := verifier.Verify(signWithKid(t, jwt.SigningMethodES256, keys.ec, "ec")); !errors.Is(err, ErrClaimMissing) {
+		t.Errorf("Expected ErrClaimMissing, got %v", err)
+	}
+}
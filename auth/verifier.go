@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/clock"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrTokenExpired        = errors.New("token is expired")                       // the token is past its "exp" claim
+	ErrSignatureInvalid    = errors.New("token signature is invalid")             // the token was not signed with the expected key
+	ErrAlgorithmNotAllowed = errors.New("token signing algorithm is not allowed") // the "alg" header is not in the allowed list, e.g. "none"
+	ErrTokenInvalid        = errors.New("token is invalid")                       // the token is malformed or fails any other check
+)
+
+// HMACAlgorithms are the algorithms accepted by VerifyToken
+var HMACAlgorithms = []string{
+	jwt.SigningMethodHS256.Alg(),
+	jwt.SigningMethodHS384.Alg(),
+	jwt.SigningMethodHS512.Alg(),
+}
+
+// Verifier verifies JWT tokens which are signed with one of an explicit list of algorithms.
+// Tokens declaring any other algorithm in their header, including "none", are rejected before their signature is checked,
+// so a token cannot pick an algorithm the key was not meant for, e.g. HS256 with an RSA public key as secret.
+type Verifier struct {
+	keyFunc        jwt.Keyfunc
+	algorithms     []string
+	parser         *jwt.Parser
+	issuers        []string
+	requiredClaims []string
+	maxAge         time.Duration
+	leeway         time.Duration
+	clock          clock.Clock
+}
+
+// NewVerifier returns a Verifier which checks signatures with key and only accepts the given algorithms.
+// The key must suit every algorithm: []byte or string for HS256, HS384 and HS512, *rsa.PublicKey for RS* and PS*,
+// *ecdsa.PublicKey for ES* and ed25519.PublicKey for EdDSA.
+//
+// Example usage:
+//
+//	verifier, err := auth.NewVerifier(publicKey, "RS256")
+//	if err != nil {
+//		return err
+//	}
+//	token, err := verifier.Verify(tokenString)
+//	if errors.Is(err, auth.ErrTokenExpired) {
+//		// ask the client to refresh the token
+//	}
+func NewVerifier(key interface{}, algorithms ...string) (*Verifier, error) {
+	keyFunc, err := staticKeyFunc(key, algorithms)
+	if err != nil {
+		return nil, err
+	}
+	return newVerifier(algorithms, keyFunc, VerifierOptions{}), nil
+}
+
+// staticKeyFunc returns a jwt.Keyfunc which verifies every token with key, after checking that key suits all algorithms
+func staticKeyFunc(key interface{}, algorithms []string) (jwt.Keyfunc, error) {
+	if secret, ok := key.(string); ok {
+		key = []byte(secret)
+	}
+	if secret, ok := key.([]byte); ok && len(secret) == 0 {
+		return nil, fmt.Errorf("failed to create token verifier: empty secret")
+	}
+	if err := checkAlgorithms(algorithms); err != nil {
+		return nil, err
+	}
+	for _, algorithm := range algorithms {
+		if !keySuitsAlgorithm(key, algorithm) {
+			return nil, fmt.Errorf("failed to create token verifier: %w: %s cannot be used with a key of type %T", ErrAlgorithmNotAllowed, algorithm, key)
+		}
+	}
+	return func(token *jwt.Token) (interface{}, error) {
+		if !keySuitsAlgorithm(key, token.Method.Alg()) {
+			return nil, fmt.Errorf("%w: %s", ErrAlgorithmNotAllowed, token.Method.Alg())
+		}
+		return key, nil
+	}, nil
+}
+
+// newVerifier returns a Verifier accepting algorithms, verifying signatures with the key returned by keyFunc
+// and validating the claims as set by options. The key settings of options are ignored.
+func newVerifier(algorithms []string, keyFunc jwt.Keyfunc, options VerifierOptions) *Verifier {
+	clk := options.Clock
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	parserOptions := []jwt.ParserOption{
+		jwt.WithValidMethods(algorithms),
+		jwt.WithLeeway(options.Leeway),
+		jwt.WithTimeFunc(clk.Now),
+	}
+	if options.Audience != "" {
+		parserOptions = append(parserOptions, jwt.WithAudience(options.Audience))
+	}
+	if options.MaxAge > 0 {
+		parserOptions = append(parserOptions, jwt.WithIssuedAt())
+	}
+	return &Verifier{
+		keyFunc:        keyFunc,
+		algorithms:     slices.Clone(algorithms),
+		parser:         jwt.NewParser(parserOptions...),
+		issuers:        slices.Clone(options.Issuers),
+		requiredClaims: slices.Clone(options.RequiredClaims),
+		maxAge:         options.MaxAge,
+		leeway:         options.Leeway,
+		clock:          clk,
+	}
+}
+
+// Verify parses tokenString and checks its algorithm, signature and expiration.
+// The returned error wraps ErrAlgorithmNotAllowed, ErrSignatureInvalid, ErrTokenExpired, ErrUnknownKey or ErrTokenInvalid,
+// or one of the errors of the claim rules set by VerifierOptions.
+func (v *Verifier) Verify(tokenString string) (*jwt.Token, error) {
+	token, err := v.parser.Parse(tokenString, v.keyFunc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse token: %w: %s", v.classify(token, err), err.Error())
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("failed to parse token: %w", ErrTokenInvalid)
+	}
+	if err := v.validateClaims(token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// classify maps an error of the jwt parser to one of the sentinel errors of this package
+func (v *Verifier) classify(token *jwt.Token, err error) error {
+	if errors.Is(err, jwt.ErrTokenMalformed) {
+		return ErrTokenInvalid
+	}
+	if token != nil {
+		if algorithm, _ := token.Header["alg"].(string); !slices.Contains(v.algorithms, algorithm) {
+			return ErrAlgorithmNotAllowed
+		}
+	}
+	switch {
+	case errors.Is(err, ErrAlgorithmNotAllowed):
+		return ErrAlgorithmNotAllowed
+	case errors.Is(err, ErrUnknownKey):
+		return ErrUnknownKey
+	case errors.Is(err, jwt.ErrTokenSignatureInvalid):
+		return ErrSignatureInvalid
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return ErrTokenExpired
+	case errors.Is(err, jwt.ErrTokenNotValidYet), errors.Is(err, jwt.ErrTokenUsedBeforeIssued):
+		return ErrTokenNotValidYet
+	case errors.Is(err, jwt.ErrTokenRequiredClaimMissing):
+		return ErrClaimMissing
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return ErrAudienceInvalid
+	default:
+		return ErrTokenInvalid
+	}
+}
+
+// checkAlgorithms rejects an empty list, "none" and algorithms unknown to the jwt package
+func checkAlgorithms(algorithms []string) error {
+	if len(algorithms) == 0 {
+		return fmt.Errorf("failed to create token verifier: no algorithm is allowed")
+	}
+	for _, algorithm := range algorithms {
+		if strings.EqualFold(algorithm, "none") || jwt.GetSigningMethod(algorithm) == nil {
+			return fmt.Errorf("failed to create token verifier: %w: %q", ErrAlgorithmNotAllowed, algorithm)
+		}
+	}
+	return nil
+}
+
+// keySuitsAlgorithm reports whether key has the type the algorithm verifies signatures with
+func keySuitsAlgorithm(key interface{}, algorithm string) bool {
+	switch jwt.GetSigningMethod(algorithm).(type) {
+	case *jwt.SigningMethodHMAC:
+		_, ok := key.([]byte)
+		return ok
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+		_, ok := key.(*rsa.PublicKey)
+		return ok
+	case *jwt.SigningMethodECDSA:
+		_, ok := key.(*ecdsa.PublicKey)
+		return ok
+	case *jwt.SigningMethodEd25519:
+		_, ok := key.(ed25519.PublicKey)
+		return ok
+	default:
+		return false
+	}
+}
@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret"
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub": "user-1",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+func TestNewVerifier(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPublicKey, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		name        string
+		key         interface{}
+		algorithms  []string
+		expectedErr error
+	}{
+		{name: "HMAC secret as string", key: testSecret, algorithms: []string{"HS256", "HS512"}},
+		{name: "HMAC secret as bytes", key: []byte(testSecret), algorithms: []string{"HS384"}},
+		{name: "RSA public key", key: &rsaKey.PublicKey, algorithms: []string{"RS256", "PS256"}},
+		{name: "ECDSA public key", key: &ecKey.PublicKey, algorithms: []string{"ES256"}},
+		{name: "Ed25519 public key", key: edPublicKey, algorithms: []string{"EdDSA"}},
+		{name: "No algorithm", key: testSecret, expectedErr: errors.New("any")},
+		{name: "Empty secret", key: "", algorithms: []string{"HS256"}, expectedErr: errors.New("any")},
+		{name: "Algorithm none", key: testSecret, algorithms: []string{"none"}, expectedErr: ErrAlgorithmNotAllowed},
+		{name: "Unknown algorithm", key: testSecret, algorithms: []string{"HS999"}, expectedErr: ErrAlgorithmNotAllowed},
+		{name: "RSA algorithm with HMAC secret", key: testSecret, algorithms: []string{"HS256", "RS256"}, expectedErr: ErrAlgorithmNotAllowed},
+		{name: "HMAC algorithm with RSA public key", key: &rsaKey.PublicKey, algorithms: []string{"HS256"}, expectedErr: ErrAlgorithmNotAllowed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			verifier, err := NewVerifier(test.key, test.algorithms...)
+			if test.expectedErr == nil {
+				if err != nil || verifier == nil {
+					t.Errorf("Expected a verifier, got error %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Expected an error, got nil")
+			}
+			if errors.Is(test.expectedErr, ErrAlgorithmNotAllowed) && !errors.Is(err, ErrAlgorithmNotAllowed) {
+				t.Errorf("Expected error wrapping %v, got %v", ErrAlgorithmNotAllowed, err)
+			}
+		})
+	}
+}
+
+func TestVerifierVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	otherRSAKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	publicKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)})
+
+	expiredClaims := validClaims()
+	expiredClaims["exp"] = time.Now().Add(-time.Minute).Unix()
+	futureClaims := validClaims()
+	futureClaims["nbf"] = time.Now().Add(time.Hour).Unix()
+
+	tests := []struct {
+		name        string
+		key         interface{}
+		algorithms  []string
+		token       string
+		expectedErr error
+	}{
+		{
+			name:       "Valid HMAC token",
+			key:        testSecret,
+			algorithms: []string{"HS256"},
+			token:      signToken(t, jwt.SigningMethodHS256, []byte(testSecret), validClaims()),
+		},
+		{
+			name:       "Valid RSA token",
+			key:        &rsaKey.PublicKey,
+			algorithms: []string{"RS256"},
+			token:      signToken(t, jwt.SigningMethodRS256, rsaKey, validClaims()),
+		},
+		{
+			name:        "Expired token",
+			key:         testSecret,
+			algorithms:  []string{"HS256"},
+			token:       signToken(t, jwt.SigningMethodHS256, []byte(testSecret), expiredClaims),
+			expectedErr: ErrTokenExpired,
+		},
+		{
+			name:        "Token signed with another secret",
+			key:         testSecret,
+			algorithms:  []string{"HS256"},
+			token:       signToken(t, jwt.SigningMethodHS256, []byte("forged"), validClaims()),
+			expectedErr: ErrSignatureInvalid,
+		},
+		{
+			name:        "Token signed with another RSA key",
+			key:         &rsaKey.PublicKey,
+			algorithms:  []string{"RS256"},
+			token:       signToken(t, jwt.SigningMethodRS256, otherRSAKey, validClaims()),
+			expectedErr: ErrSignatureInvalid,
+		},
+		{
+			name:        "Unsigned token",
+			key:         testSecret,
+			algorithms:  []string{"HS256"},
+			token:       signToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, validClaims()),
+			expectedErr: ErrAlgorithmNotAllowed,
+		},
+		{
+			name:        "Allowed family but not allowed algorithm",
+			key:         testSecret,
+			algorithms:  []string{"HS256"},
+			token:       signToken(t, jwt.SigningMethodHS512, []byte(testSecret), validClaims()),
+			expectedErr: ErrAlgorithmNotAllowed,
+		},
+		{
+			name:        "HMAC token signed with the RSA public key as secret",
+			key:         &rsaKey.PublicKey,
+			algorithms:  []string{"RS256"},
+			token:       signToken(t, jwt.SigningMethodHS256, publicKeyPEM, validClaims()),
+			expectedErr: ErrAlgorithmNotAllowed,
+		},
+		{
+			name:        "Token not valid yet",
+			key:         testSecret,
+			algorithms:  []string{"HS256"},
+			token:       signToken(t, jwt.SigningMethodHS256, []byte(testSecret), futureClaims),
+			expectedErr: ErrTokenNotValidYet,
+		},
+		{
+			name:        "Malformed token",
+			key:         testSecret,
+			algorithms:  []string{"HS256"},
+			token:       "not.a.token",
+			expectedErr: ErrTokenInvalid,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			verifier, err := NewVerifier(test.key, test.algorithms...)
+			if err != nil {
+				t.Fatalf("failed to create verifier: %v", err)
+			}
+
+			token, err := verifier.Verify(test.token)
+			if test.expectedErr == nil {
+				if err != nil || token == nil || !token.Valid {
+					t.Errorf("Expected a valid token, got error %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, test.expectedErr) {
+				t.Errorf("Expected error wrapping %v, got %v", test.expectedErr, err)
+			}
+			if token != nil {
+				t.Error("Expected no token on error")
+			}
+		})
+	}
+}
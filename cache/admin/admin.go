@@ -0,0 +1,217 @@
+// Package admin provides an HTTP handler to inspect and purge a cache while it is running.
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/auth"
+	"github.com/AnhCaooo/go-goods/cache"
+	goodsContext "github.com/AnhCaooo/go-goods/context"
+	"github.com/AnhCaooo/go-goods/encode"
+	goodsHTTP "github.com/AnhCaooo/go-goods/http"
+	"github.com/AnhCaooo/go-goods/middleware"
+)
+
+// Inspectable is a cache which the admin handler can inspect and purge.
+// It is implemented by cache.Cache, cache.ShardedCache and cache.ReplicatedCache, which also purges the other replicas.
+type Inspectable interface {
+	All() iter.Seq2[string, interface{}]
+	Peek(key string) (interface{}, bool)
+	TTL(key string) (time.Duration, bool)
+	Delete(key string)
+	DeletePrefix(prefix string)
+	InvalidateTag(tag string)
+}
+
+var (
+	_ Inspectable = (*cache.Cache)(nil)
+	_ Inspectable = (*cache.ShardedCache)(nil)
+	_ Inspectable = (*cache.ReplicatedCache)(nil)
+)
+
+// Config configures the admin handler. Exactly one of JWTSecret and Verifier must be set.
+type Config struct {
+	JWTSecret string               // shared secret verifying the token of every request, a shortcut for Verifier with only Secret set
+	Verifier  auth.VerifierOptions // verifies the token of every request, e.g. against a key set, issuer and audience
+	// Authorize decides whether the authenticated user may use the handler. Every request is denied when it is nil,
+	// since a valid token alone, e.g. of any end user of the identity provider, must not expose the cache.
+	Authorize func(user goodsContext.UserContext, r *http.Request) bool
+	Sizer     cache.Sizer // measures the size of values, default is cache.DefaultSizer
+}
+
+// AllowUsers returns an Authorize function which only allows the given user IDs
+func AllowUsers(userIDs ...string) func(user goodsContext.UserContext, r *http.Request) bool {
+	return func(user goodsContext.UserContext, r *http.Request) bool {
+		return user.UserID != "" && slices.Contains(userIDs, user.UserID)
+	}
+}
+
+// Entry describes a cached entry
+type Entry struct {
+	Key        string          `json:"key"`
+	ExpiresAt  time.Time       `json:"expires_at"`
+	TTLSeconds float64         `json:"ttl_seconds"`
+	Size       int64           `json:"size"`
+	Value      json.RawMessage `json:"value,omitempty"` // only set when a single entry is requested
+}
+
+type handler struct {
+	cache Inspectable
+	sizer cache.Sizer
+}
+
+// NewHandler returns an http.Handler to inspect and purge c. Every request needs a token accepted by middleware.AuthenticateWithOptions
+// and a user allowed by config.Authorize. It returns an error if the token verification is misconfigured.
+// It serves the following endpoints, relative to where it is mounted:
+//
+//	GET    /keys?prefix=user:&limit=100  lists keys with their remaining TTL and size, sorted by key
+//	GET    /keys/{key}                   shows a single entry including its value as JSON
+//	DELETE /keys/{key}                   purges a single entry
+//	DELETE /prefixes/{prefix}            purges every entry whose key starts with prefix
+//	DELETE /tags/{tag}                   purges every entry tagged with tag
+//
+// Example usage:
+//
+//	handler, err := admin.NewHandler(c, admin.Config{
+//		JWTSecret: jwtSecret,
+//		Authorize: admin.AllowUsers(operatorID),
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	mux.Handle("/admin/cache/", http.StripPrefix("/admin/cache", handler))
+func NewHandler(c Inspectable, config Config) (http.Handler, error) {
+	options := config.Verifier
+	if config.JWTSecret != "" {
+		if options.Secret != "" || options.KeySet != nil {
+			return nil, fmt.Errorf("failed to create cache admin handler: only one of JWTSecret and Verifier can be set")
+		}
+		options.Secret = config.JWTSecret
+	}
+
+	h := &handler{cache: c, sizer: config.Sizer}
+	if h.sizer == nil {
+		h.sizer = cache.DefaultSizer
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /keys", h.listKeys)
+	mux.HandleFunc("GET /keys/{key...}", h.getEntry)
+	mux.HandleFunc("DELETE /keys/{key...}", h.purge(c.Delete))
+	mux.HandleFunc("DELETE /prefixes/{prefix...}", h.purge(c.DeletePrefix))
+	mux.HandleFunc("DELETE /tags/{tag...}", h.purge(c.InvalidateTag))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		goodsHTTP.Error(w, http.StatusNotFound, fmt.Sprintf("unknown cache admin endpoint %s %s", r.Method, r.URL.Path), goodsHTTP.NotFound)
+	})
+	authorized := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _ := r.Context().Value(goodsContext.ContextKey).(goodsContext.UserContext)
+		if config.Authorize == nil || !config.Authorize(user, r) {
+			goodsHTTP.Error(w, http.StatusForbidden, "Not allowed to administer the cache", goodsHTTP.Unauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+	handler, err := middleware.AuthenticateWithOptions(authorized, nil, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cache admin handler: %s", err.Error())
+	}
+	return handler, nil
+}
+
+func (h *handler) listKeys(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	limit := -1
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			goodsHTTP.Error(w, http.StatusBadRequest, fmt.Sprintf("invalid limit %q", value), goodsHTTP.InvalidRequest)
+			return
+		}
+		limit = n
+	}
+
+	now := time.Now()
+	entries := []Entry{}
+	for key, value := range h.cache.All() {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if entry, found := h.describe(key, value, now); found {
+			entries = append(entries, entry)
+		}
+	}
+	slices.SortFunc(entries, func(a, b Entry) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	if limit >= 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	if err := encode.EncodeResponse(w, http.StatusOK, entries); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (h *handler) getEntry(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	value, found := h.cache.Peek(key)
+	if !found {
+		goodsHTTP.Error(w, http.StatusNotFound, fmt.Sprintf("cache entry %q was not found", key), goodsHTTP.CacheEntryNotFound)
+		return
+	}
+	entry, found := h.describe(key, value, time.Now())
+	if !found {
+		goodsHTTP.Error(w, http.StatusNotFound, fmt.Sprintf("cache entry %q was not found", key), goodsHTTP.CacheEntryNotFound)
+		return
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		goodsHTTP.Error(w, http.StatusInternalServerError, fmt.Sprintf("failed to encode cache entry %q: %s", key, err.Error()), goodsHTTP.EncodeCacheEntry)
+		return
+	}
+	entry.Value = encoded
+
+	if err := encode.EncodeResponse(w, http.StatusOK, entry); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// purge returns a handler which removes the entries selected by the only path value of its route
+func (h *handler) purge(remove func(string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var value string
+		for _, name := range []string{"key", "prefix", "tag"} {
+			if v := r.PathValue(name); v != "" {
+				value = v
+			}
+		}
+		if value == "" {
+			goodsHTTP.Error(w, http.StatusBadRequest, "nothing to purge was given", goodsHTTP.InvalidRequest)
+			return
+		}
+		remove(value)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// describe returns the metadata of an entry, or false if it expired in the meantime
+func (h *handler) describe(key string, value interface{}, now time.Time) (Entry, bool) {
+	ttl, found := h.cache.TTL(key)
+	if !found {
+		return Entry{}, false
+	}
+	return Entry{
+		Key:        key,
+		ExpiresAt:  now.Add(ttl),
+		TTLSeconds: ttl.Seconds(),
+		Size:       h.sizer.Size(value),
+	}, true
+}
@@ -0,0 +1,25 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/cache"
+	"go.uber.org/zap"
+)
+
+func BenchmarkListKeys(b *testing.B) {
+	c := cache.NewCache(zap.NewNop())
+	for i := 0; i < 1000; i++ {
+		c.SetExpiredAfterTimePeriod(fmt.Sprintf("user:%d", i), i, time.Hour)
+	}
+	h := &handler{cache: c, sizer: cache.DefaultSizer}
+	r := httptest.NewRequest(http.MethodGet, "/keys?prefix=user:1&limit=100", nil)
+
+	for b.Loop() {
+		h.listKeys(httptest.NewRecorder(), r)
+	}
+}
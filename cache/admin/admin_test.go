@@ -0,0 +1,314 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/auth"
+	"github.com/AnhCaooo/go-goods/cache"
+	goodsContext "github.com/AnhCaooo/go-goods/context"
+	goodsHTTP "github.com/AnhCaooo/go-goods/http"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+const testSecret = "admin-secret"
+
+func newTestHandler(t *testing.T) (http.Handler, *cache.Cache) {
+	t.Helper()
+	c := cache.NewCache(zap.NewNop())
+	c.SetWithTags("user:1", "alice", time.Hour, "team:a")
+	c.SetWithTags("user:2", "bob", time.Hour, "team:b")
+	c.SetExpiredAfterTimePeriod("post:1", map[string]string{"title": "hello"}, time.Minute)
+	c.SetExpiredAfterTimePeriod("expired", "old", -time.Minute)
+	c.SetExpiredAfterTimePeriod("func", func() {}, time.Minute)
+	h, err := NewHandler(c, Config{JWTSecret: testSecret, Authorize: AllowUsers("admin")})
+	if err != nil {
+		t.Fatalf("failed to create handler: %v", err)
+	}
+	return h, c
+}
+
+func testToken(t *testing.T, secret string) string {
+	t.Helper()
+	return testTokenFor(t, secret, "admin")
+}
+
+func testTokenFor(t *testing.T, secret string, subject string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":        subject,
+		"session_id": "session",
+	}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+func request(t *testing.T, h http.Handler, method string, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, target, nil)
+	r.Header.Set("Authorization", "Bearer "+testToken(t, testSecret))
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, r)
+	return recorder
+}
+
+func decodeError(t *testing.T, recorder *httptest.ResponseRecorder) goodsHTTP.HTTPError {
+	t.Helper()
+	var httpError goodsHTTP.HTTPError
+	if err := json.NewDecoder(recorder.Body).Decode(&httpError); err != nil {
+		t.Fatalf("failed to decode error response: %v", err)
+	}
+	return httpError
+}
+
+func TestAuthentication(t *testing.T) {
+	tests := []struct {
+		name           string
+		authorization  string
+		expectedStatus int
+	}{
+		{name: "Missing token", expectedStatus: http.StatusForbidden},
+		{name: "Token signed with another secret", authorization: "Bearer " + testToken(t, "other"), expectedStatus: http.StatusUnauthorized},
+		{name: "Valid token", authorization: "Bearer " + testToken(t, testSecret), expectedStatus: http.StatusOK},
+		{name: "Valid token of a user who is not allowed", authorization: "Bearer " + testTokenFor(t, testSecret, "user-1"), expectedStatus: http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, _ := newTestHandler(t)
+			r := httptest.NewRequest(http.MethodGet, "/keys", nil)
+			if test.authorization != "" {
+				r.Header.Set("Authorization", test.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			h.ServeHTTP(recorder, r)
+
+			if recorder.Code != test.expectedStatus {
+				t.Errorf("Expected status %d, got %d", test.expectedStatus, recorder.Code)
+			}
+		})
+	}
+}
+
+func TestListKeys(t *testing.T) {
+	tests := []struct {
+		name           string
+		target         string
+		expectedStatus int
+		expectedKeys   []string
+	}{
+		{name: "All keys", target: "/keys", expectedStatus: http.StatusOK, expectedKeys: []string{"func", "post:1", "user:1", "user:2"}},
+		{name: "Keys with prefix", target: "/keys?prefix=user:", expectedStatus: http.StatusOK, expectedKeys: []string{"user:1", "user:2"}},
+		{name: "Limited keys", target: "/keys?limit=1", expectedStatus: http.StatusOK, expectedKeys: []string{"func"}},
+		{name: "No matching keys", target: "/keys?prefix=none", expectedStatus: http.StatusOK, expectedKeys: []string{}},
+		{name: "Invalid limit", target: "/keys?limit=-1", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, _ := newTestHandler(t)
+			recorder := request(t, h, http.MethodGet, test.target)
+
+			if recorder.Code != test.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", test.expectedStatus, recorder.Code)
+			}
+			if test.expectedStatus != http.StatusOK {
+				if httpError := decodeError(t, recorder); httpError.TranslationKey != goodsHTTP.InvalidRequest {
+					t.Errorf("Expected translation key %q, got %q", goodsHTTP.InvalidRequest, httpError.TranslationKey)
+				}
+				return
+			}
+
+			var entries []Entry
+			if err := json.NewDecoder(recorder.Body).Decode(&entries); err != nil {
+				t.Fatalf("failed to decode entries: %v", err)
+			}
+			keys := []string{}
+			for _, entry := range entries {
+				keys = append(keys, entry.Key)
+				if entry.TTLSeconds <= 0 || entry.Size <= 0 || entry.ExpiresAt.IsZero() {
+					t.Errorf("Expected TTL, size and expiration of %q, got %+v", entry.Key, entry)
+				}
+			}
+			if !reflect.DeepEqual(keys, test.expectedKeys) {
+				t.Errorf("Expected keys %v, got %v", test.expectedKeys, keys)
+			}
+		})
+	}
+}
+
+func TestGetEntry(t *testing.T) {
+	tests := []struct {
+		name                   string
+		key                    string
+		expectedStatus         int
+		expectedValue          string
+		expectedTranslationKey goodsHTTP.TranslationKey
+	}{
+		{name: "String value", key: "user:1", expectedStatus: http.StatusOK, expectedValue: `"alice"`},
+		{name: "Map value", key: "post:1", expectedStatus: http.StatusOK, expectedValue: `{"title":"hello"}`},
+		{name: "Missing entry", key: "user:3", expectedStatus: http.StatusNotFound, expectedTranslationKey: goodsHTTP.CacheEntryNotFound},
+		{name: "Expired entry", key: "expired", expectedStatus: http.StatusNotFound, expectedTranslationKey: goodsHTTP.CacheEntryNotFound},
+		{name: "Value without JSON encoding", key: "func", expectedStatus: http.StatusInternalServerError, expectedTranslationKey: goodsHTTP.EncodeCacheEntry},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, c := newTestHandler(t)
+			recorder := request(t, h, http.MethodGet, "/keys/"+test.key)
+
+			if recorder.Code != test.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", test.expectedStatus, recorder.Code)
+			}
+			if test.expectedStatus != http.StatusOK {
+				if httpError := decodeError(t, recorder); httpError.TranslationKey != test.expectedTranslationKey {
+					t.Errorf("Expected translation key %q, got %q", test.expectedTranslationKey, httpError.TranslationKey)
+				}
+				return
+			}
+
+			var entry Entry
+			if err := json.NewDecoder(recorder.Body).Decode(&entry); err != nil {
+				t.Fatalf("failed to decode entry: %v", err)
+			}
+			if entry.Key != test.key || string(entry.Value) != test.expectedValue {
+				t.Errorf("Expected %s=%s, got %s=%s", test.key, test.expectedValue, entry.Key, entry.Value)
+			}
+			if stats := c.Stats(); stats.Hits != 0 {
+				t.Errorf("Inspecting an entry should not count as a hit, got %+v", stats)
+			}
+		})
+	}
+}
+
+func TestPurge(t *testing.T) {
+	tests := []struct {
+		name            string
+		target          string
+		expectedStatus  int
+		expectedPresent []string
+		expectedAbsent  []string
+	}{
+		{
+			name:            "Purge key",
+			target:          "/keys/user:1",
+			expectedStatus:  http.StatusNoContent,
+			expectedPresent: []string{"user:2", "post:1"},
+			expectedAbsent:  []string{"user:1"},
+		},
+		{
+			name:            "Purge prefix",
+			target:          "/prefixes/user:",
+			expectedStatus:  http.StatusNoContent,
+			expectedPresent: []string{"post:1"},
+			expectedAbsent:  []string{"user:1", "user:2"},
+		},
+		{
+			name:            "Purge tag",
+			target:          "/tags/team:b",
+			expectedStatus:  http.StatusNoContent,
+			expectedPresent: []string{"user:1", "post:1"},
+			expectedAbsent:  []string{"user:2"},
+		},
+		{
+			name:            "Empty prefix is rejected",
+			target:          "/prefixes/",
+			expectedStatus:  http.StatusBadRequest,
+			expectedPresent: []string{"user:1", "user:2", "post:1"},
+		},
+		{
+			name:            "Unknown endpoint",
+			target:          "/everything",
+			expectedStatus:  http.StatusNotFound,
+			expectedPresent: []string{"user:1", "user:2", "post:1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, c := newTestHandler(t)
+			recorder := request(t, h, http.MethodDelete, test.target)
+
+			if recorder.Code != test.expectedStatus {
+				t.Errorf("Expected status %d, got %d", test.expectedStatus, recorder.Code)
+			}
+			for _, key := range test.expectedPresent {
+				if _, found := c.Peek(key); !found {
+					t.Errorf("Key %q should still exist", key)
+				}
+			}
+			for _, key := range test.expectedAbsent {
+				if _, found := c.Peek(key); found {
+					t.Errorf("Key %q should have been purged", key)
+				}
+			}
+		})
+	}
+}
+
+func TestNewHandlerConfig(t *testing.T) {
+	c := cache.NewCache(zap.NewNop())
+	tests := []struct {
+		name           string
+		config         Config
+		expectedErr    bool
+		expectedStatus int
+	}{
+		{
+			name:           "Secret without Authorize denies every request",
+			config:         Config{JWTSecret: testSecret},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Verifier options",
+			config:         Config{Verifier: auth.VerifierOptions{Secret: testSecret, RequiredClaims: []string{"session_id"}}, Authorize: AllowUsers("admin")},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "Verifier options rejecting the token",
+			config: Config{
+				Verifier:  auth.VerifierOptions{Secret: testSecret, Issuers: []string{"https://auth.example.com"}},
+				Authorize: AllowUsers("admin"),
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "Authorize by request",
+			config: Config{JWTSecret: testSecret, Authorize: func(user goodsContext.UserContext, r *http.Request) bool {
+				return r.Method == http.MethodGet
+			}},
+			expectedStatus: http.StatusOK,
+		},
+		{name: "Neither secret nor verifier", config: Config{Authorize: AllowUsers("admin")}, expectedErr: true},
+		{
+			name:        "Both secret and verifier",
+			config:      Config{JWTSecret: testSecret, Verifier: auth.VerifierOptions{Secret: testSecret}, Authorize: AllowUsers("admin")},
+			expectedErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, err := NewHandler(c, test.config)
+			if test.expectedErr {
+				if err == nil {
+					t.Error("Expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to create handler: %v", err)
+			}
+			if recorder := request(t, h, http.MethodGet, "/keys"); recorder.Code != test.expectedStatus {
+				t.Errorf("Expected status %d, got %d", test.expectedStatus, recorder.Code)
+			}
+		})
+	}
+}
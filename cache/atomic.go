@@ -0,0 +1,181 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+)
+
+// KeepTTL can be passed to Update as ttl to keep the expiration of the existing entry
+const KeepTTL time.Duration = math.MinInt64
+
+// ErrNotNumeric is returned by Increment and Decrement when the cached value is not a number
+var ErrNotNumeric = errors.New("cache value is not a number")
+
+// Increment atomically adds delta to the numeric value of key and returns the result.
+// An existing value keeps its expiration. A missing or expired value is treated as zero and the result is cached for ttl,
+// as an int64 when V is an interface type. It returns ErrNotNumeric if the value is not an integer or floating point number.
+//
+// Example usage:
+//
+//	requests, err := c.Increment("requests:"+userID, 1, time.Minute)
+//	if err == nil && requests > limit {
+//		// reject the request
+//	}
+func (c *Typed[K, V]) Increment(key K, delta int64, ttl time.Duration) (V, error) {
+	c.lock.Lock()
+	defer c.unlock()
+
+	now := c.clock.Now()
+	entry, found := c.live(key, now)
+	if !found {
+		entry = Entry[V]{Expiration: now.Add(ttl), ttl: ttl}
+	}
+
+	var current any = entry.Value
+	if !found && current == nil {
+		current = int64(0)
+	}
+	result, err := addNumber(current, delta)
+	if err != nil {
+		var zero V
+		return zero, fmt.Errorf("failed to increment cache key %v: %w", key, err)
+	}
+	value, ok := result.(V)
+	if !ok {
+		var zero V
+		return zero, fmt.Errorf("failed to increment cache key %v: %w: %T", key, ErrNotNumeric, zero)
+	}
+	entry.Value = value
+	c.modify(key, entry)
+	return entry.Value, nil
+}
+
+// Decrement atomically subtracts delta from the numeric value of key and returns the result. See Increment for details.
+func (c *Typed[K, V]) Decrement(key K, delta int64, ttl time.Duration) (V, error) {
+	return c.Increment(key, -delta, ttl)
+}
+
+// CompareAndSwap atomically replaces the value of key with new if its current value is deeply equal to old,
+// and reports whether it was replaced. The entry keeps its expiration. A missing or expired value is never replaced.
+func (c *Typed[K, V]) CompareAndSwap(key K, old V, new V) bool {
+	c.lock.Lock()
+	defer c.unlock()
+
+	entry, found := c.live(key, c.clock.Now())
+	if !found || !reflect.DeepEqual(entry.Value, old) {
+		return false
+	}
+	entry.Value = new
+	c.modify(key, entry)
+	return true
+}
+
+// SetIfAbsent caches the value for ttl only if key has no live value yet, and reports whether it was stored.
+func (c *Typed[K, V]) SetIfAbsent(key K, value V, ttl time.Duration) bool {
+	c.lock.Lock()
+	defer c.unlock()
+
+	now := c.clock.Now()
+	if _, found := c.live(key, now); found {
+		return false
+	}
+	c.store(key, Entry[V]{
+		Value:      value,
+		Expiration: now.Add(ttl),
+		ttl:        ttl,
+	})
+	return true
+}
+
+// Take atomically returns and removes the live value of key, so only one of concurrent callers gets it.
+// It returns the zero value of V and `false` if the value is missing or expired. The removal is reported with ReasonDeleted.
+//
+// Example usage:
+//
+//	if _, found := c.Take("reset-code:" + code); !found {
+//		// the code is unknown or was already used
+//	}
+func (c *Typed[K, V]) Take(key K) (V, bool) {
+	c.lock.Lock()
+	defer c.unlock()
+
+	entry, found := c.live(key, c.clock.Now())
+	if !found {
+		var zero V
+		return zero, false
+	}
+	c.remove(key, ReasonDeleted)
+	return entry.Value, true
+}
+
+// Update atomically replaces the value of key with the result of fn, which receives the current value
+// and whether it was found, and returns the new value. The new value is cached for ttl, or keeps the expiration
+// of the existing entry if ttl is KeepTTL. With KeepTTL, nothing is stored when the key has no live value.
+// fn runs while the cache is locked, so it must be fast and must not use the cache.
+//
+// Example usage:
+//
+//	c.Update("window:"+userID, cache.KeepTTL, func(current []time.Time, found bool) []time.Time {
+//		return append(current, time.Now())
+//	})
+func (c *Typed[K, V]) Update(key K, ttl time.Duration, fn func(current V, found bool) V) V {
+	c.lock.Lock()
+	defer c.unlock()
+
+	now := c.clock.Now()
+	entry, found := c.live(key, now)
+	value := fn(entry.Value, found)
+	switch {
+	case ttl != KeepTTL:
+		entry = Entry[V]{Expiration: now.Add(ttl), ttl: ttl}
+	case !found:
+		return value
+	}
+	entry.Value = value
+	c.modify(key, entry)
+	return value
+}
+
+// live returns the entry of key if it has not expired at now, removing it if it has. The caller must hold the lock.
+func (c *Typed[K, V]) live(key K, now time.Time) (Entry[V], bool) {
+	entry, exists := c.data[key]
+	if !exists {
+		return Entry[V]{}, false
+	}
+	if now.After(entry.Expiration) {
+		c.remove(key, ReasonExpired)
+		return Entry[V]{}, false
+	}
+	return entry, true
+}
+
+// modify stores a changed entry. Unlike a plain overwrite, the entry keeps its tags. The caller must hold the lock.
+func (c *Typed[K, V]) modify(key K, entry Entry[V]) {
+	tags := c.keyTags[key]
+	c.store(key, entry)
+	c.tag(key, tags)
+}
+
+// addNumber adds delta to a value of any integer or floating point kind, keeping its type
+func addNumber(value any, delta int64) (any, error) {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() {
+		return nil, fmt.Errorf("%w: %v", ErrNotNumeric, value)
+	}
+
+	result := reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		result.SetInt(v.Int() + delta)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		result.SetUint(v.Uint() + uint64(delta))
+	case reflect.Float32, reflect.Float64:
+		result.SetFloat(v.Float() + float64(delta))
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrNotNumeric, value)
+	}
+	return result.Interface(), nil
+}
@@ -0,0 +1,320 @@
+package cache
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/clock"
+	"go.uber.org/zap"
+)
+
+type counter int32
+
+func TestIncrement(t *testing.T) {
+	tests := []struct {
+		name        string
+		initial     interface{} // nil means the key is missing
+		delta       int64
+		expected    interface{}
+		expectError bool
+	}{
+		{name: "Missing key starts from zero as int64", delta: 2, expected: int64(2)},
+		{name: "Int", initial: 40, delta: 2, expected: 42},
+		{name: "Uint8", initial: uint8(1), delta: 2, expected: uint8(3)},
+		{name: "Float64", initial: 1.5, delta: 2, expected: 3.5},
+		{name: "Named integer type", initial: counter(1), delta: -3, expected: counter(-2)},
+		{name: "String is not numeric", initial: "1", delta: 1, expected: "1", expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := NewCache(zap.NewNop())
+			if test.initial != nil {
+				c.SetExpiredAfterTimePeriod("key", test.initial, time.Minute)
+			}
+
+			result, err := c.Increment("key", test.delta, time.Minute)
+			if test.expectError {
+				if !errors.Is(err, ErrNotNumeric) {
+					t.Fatalf("Expected ErrNotNumeric, got %v", err)
+				}
+			} else if err != nil || result != test.expected {
+				t.Fatalf("Expected (%#v, nil), got (%#v, %v)", test.expected, result, err)
+			}
+
+			if value, _ := c.Get("key"); value != test.expected {
+				t.Errorf("Expected cached %#v, got %#v", test.expected, value)
+			}
+		})
+	}
+}
+
+func TestIncrementKeepsExpirationAndTags(t *testing.T) {
+	c := NewTyped[string, int](zap.NewNop())
+	expiration := time.Now().Add(time.Hour)
+	c.SetExpiredAtTime("key", 1, expiration)
+	c.tag("key", []string{"user:1"})
+
+	if result, err := c.Decrement("key", 3, time.Minute); err != nil || result != -2 {
+		t.Fatalf("Expected (-2, nil), got (%v, %v)", result, err)
+	}
+	if got := c.data["key"].Expiration; !got.Equal(expiration) {
+		t.Errorf("Expected expiration %v, got %v", expiration, got)
+	}
+
+	c.InvalidateTag("user:1")
+	if _, found := c.Get("key"); found {
+		t.Error("Incremented entry should keep its tags")
+	}
+}
+
+func TestIncrementExpiredStartsOver(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	c := NewTyped[string, int](zap.NewNop(), WithClock(clk))
+	c.SetExpiredAfterTimePeriod("key", 41, time.Minute)
+	clk.Advance(2 * time.Minute)
+
+	if result, err := c.Increment("key", 1, time.Minute); err != nil || result != 1 {
+		t.Errorf("Expected (1, nil), got (%v, %v)", result, err)
+	}
+}
+
+func TestIncrementConcurrently(t *testing.T) {
+	c := NewTyped[string, int64](zap.NewNop())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.Increment("key", 1, time.Minute)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if value, _ := c.Get("key"); value != 5000 {
+		t.Errorf("Expected 5000, got %d", value)
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	tests := []struct {
+		name     string
+		set      func(c *Typed[string, []string])
+		old      []string
+		expected bool
+		value    []string
+	}{
+		{
+			name:     "Equal value is swapped",
+			set:      func(c *Typed[string, []string]) { c.SetExpiredAfterTimePeriod("key", []string{"a"}, time.Minute) },
+			old:      []string{"a"},
+			expected: true,
+			value:    []string{"b"},
+		},
+		{
+			name:  "Different value is kept",
+			set:   func(c *Typed[string, []string]) { c.SetExpiredAfterTimePeriod("key", []string{"a"}, time.Minute) },
+			old:   []string{"c"},
+			value: []string{"a"},
+		},
+		{
+			name: "Missing key is not stored",
+			set:  func(c *Typed[string, []string]) {},
+		},
+		{
+			name: "Expired value is not swapped",
+			set:  func(c *Typed[string, []string]) { c.SetExpiredAfterTimePeriod("key", []string{"a"}, -time.Second) },
+			old:  []string{"a"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := NewTyped[string, []string](zap.NewNop())
+			test.set(c)
+
+			if swapped := c.CompareAndSwap("key", test.old, []string{"b"}); swapped != test.expected {
+				t.Errorf("Expected swapped=%v, got %v", test.expected, swapped)
+			}
+			if value, _ := c.Get("key"); !reflect.DeepEqual(value, test.value) {
+				t.Errorf("Expected %v, got %v", test.value, value)
+			}
+		})
+	}
+}
+
+func TestSetIfAbsent(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	c := NewTyped[string, string](zap.NewNop(), WithClock(clk))
+	c.SetExpiredAfterTimePeriod("expired", "old", time.Minute)
+	clk.Advance(2 * time.Minute)
+
+	if !c.SetIfAbsent("key", "first", time.Minute) {
+		t.Error("Missing key should be stored")
+	}
+	if c.SetIfAbsent("key", "second", time.Minute) {
+		t.Error("Existing key should not be overwritten")
+	}
+	if !c.SetIfAbsent("expired", "new", time.Minute) {
+		t.Error("Expired key should be stored")
+	}
+
+	for key, expected := range map[string]string{"key": "first", "expired": "new"} {
+		if value, _ := c.Get(key); value != expected {
+			t.Errorf("Key %s: expected %s, got %s", key, expected, value)
+		}
+	}
+}
+
+func TestSetIfAbsentConcurrently(t *testing.T) {
+	c := NewTyped[string, int](zap.NewNop())
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	stored := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if c.SetIfAbsent("lock", i, time.Minute) {
+				lock.Lock()
+				stored++
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if stored != 1 {
+		t.Errorf("Expected exactly one caller to store the value, got %d", stored)
+	}
+}
+
+func TestTake(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	c := NewTyped[string, int](zap.NewNop(), WithClock(clk))
+	var evicted []EvictionReason
+	c.OnEvict(func(key string, value int, reason EvictionReason) {
+		evicted = append(evicted, reason)
+	})
+	c.SetExpiredAfterTimePeriod("key", 1, time.Minute)
+	c.SetExpiredAfterTimePeriod("expired", 2, time.Second)
+	clk.Advance(2 * time.Second)
+
+	tests := []struct {
+		name          string
+		key           string
+		expectedValue int
+		expectedFound bool
+	}{
+		{name: "Live value", key: "key", expectedValue: 1, expectedFound: true},
+		{name: "Value already taken", key: "key"},
+		{name: "Expired value", key: "expired"},
+		{name: "Missing value", key: "missing"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, found := c.Take(test.key)
+			if value != test.expectedValue || found != test.expectedFound {
+				t.Errorf("Expected (%d, %v), got (%d, %v)", test.expectedValue, test.expectedFound, value, found)
+			}
+		})
+	}
+	if len(evicted) == 0 || evicted[0] != ReasonDeleted {
+		t.Errorf("Expected the taken value to be reported as deleted, got %v", evicted)
+	}
+}
+
+func TestTakeConcurrently(t *testing.T) {
+	c := NewTyped[string, int](zap.NewNop())
+	c.SetExpiredAfterTimePeriod("code", 1, time.Minute)
+
+	var wg sync.WaitGroup
+	var taken atomic.Int32
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, found := c.Take("code"); found {
+				taken.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := taken.Load(); n != 1 {
+		t.Errorf("Expected exactly one caller to take the value, got %d", n)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	appendOne := func(current []int, found bool) []int {
+		return append(current, 1)
+	}
+
+	tests := []struct {
+		name               string
+		set                func(c *Typed[string, []int])
+		ttl                time.Duration
+		expected           []int
+		expectedFound      bool
+		expectedExpiration time.Duration
+	}{
+		{
+			name:               "Keep the expiration",
+			set:                func(c *Typed[string, []int]) { c.SetExpiredAfterTimePeriod("key", []int{0}, time.Hour) },
+			ttl:                KeepTTL,
+			expected:           []int{0, 1},
+			expectedFound:      true,
+			expectedExpiration: time.Hour,
+		},
+		{
+			name:               "Reset the expiration",
+			set:                func(c *Typed[string, []int]) { c.SetExpiredAfterTimePeriod("key", []int{0}, time.Hour) },
+			ttl:                time.Minute,
+			expected:           []int{0, 1},
+			expectedFound:      true,
+			expectedExpiration: time.Minute,
+		},
+		{
+			name:               "Missing key with a TTL is stored",
+			set:                func(c *Typed[string, []int]) {},
+			ttl:                time.Minute,
+			expected:           []int{1},
+			expectedFound:      true,
+			expectedExpiration: time.Minute,
+		},
+		{
+			name: "Missing key with KeepTTL is not stored",
+			set:  func(c *Typed[string, []int]) {},
+			ttl:  KeepTTL,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Now())
+			c := NewTyped[string, []int](zap.NewNop(), WithClock(clk))
+			now := clk.Now()
+			test.set(c)
+
+			c.Update("key", test.ttl, appendOne)
+
+			value, found := c.Get("key")
+			if found != test.expectedFound || !reflect.DeepEqual(value, test.expected) {
+				t.Fatalf("Expected (%v, %v), got (%v, %v)", test.expected, test.expectedFound, value, found)
+			}
+			if found {
+				assertExpiresIn(t, "Entry", c.data["key"].Expiration, now, test.expectedExpiration)
+			}
+		})
+	}
+}
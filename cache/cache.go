@@ -2,106 +2,58 @@ package cache
 
 import (
 	"strings"
-	"sync"
-	"time"
 
 	"go.uber.org/zap"
 )
 
 // Cache represents a thread-safe in-memory cache with logging capabilities.
-// It contains a map to store cache values, a logger for logging purposes,
-// and a mutex to ensure safe concurrent access.
+// It is a thin adapter over Typed that stores values as interface{},
+// so existing callers keep working while new code can use Typed directly.
+//
+// SetExpiredAfterTimePeriod, SetExpiredAtTime, Get and Delete are provided by the embedded Typed cache.
 type Cache struct {
-	Data   map[string]CacheValue
-	logger *zap.Logger
-	lock   sync.Mutex
+	*Typed[string, interface{}]
+	// Data is the map backing the cache.
+	//
+	// Deprecated: Data is read and written without the lock of the cache, so using it races with every other method.
+	// Use Get, GetMany, All, Keys, Len and TTL to inspect the cache instead. Data will be removed in the next major version.
+	Data  map[string]CacheValue
+	types *TypeCodec
 }
 
 // CacheValue represents a value stored in the cache along with its expiration time.
 // Value is the actual data being cached.
 // Expiration is the time at which the cached value will expire and should be considered invalid.
-type CacheValue struct {
-	Value      interface{}
-	Expiration time.Time
-}
-
-// NewCache returns a new Cache instance
-func NewCache(logger *zap.Logger) *Cache {
-	return &Cache{
-		Data:   make(map[string]CacheValue),
-		logger: logger,
+type CacheValue = Entry[interface{}]
+
+// NewCache returns a new Cache instance. Optional behaviour such as a maximum number of entries is configured by opts.
+func NewCache(logger *zap.Logger, opts ...Option) *Cache {
+	data := make(map[string]CacheValue)
+	c := &Cache{
+		Typed: newTyped[string, interface{}](logger, data, newOptions(opts)),
+		Data:  data,
+		types: NewTypeCodec(),
 	}
+	c.codec = c.types
+	return c
 }
 
-// a method is used to add new key-value pair to the cache.
-// It takes in a key, a value, and a duration representing the expiration time of the value.
-// It first acquires a lock on the mutex to ensure thread safety, and then it adds the key-value pair to the map along with the expiration time.
-// Finally, it releases the lock.
-func (c *Cache) SetExpiredAfterTimePeriod(key string, value interface{}, duration time.Duration) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-
-	expirationTime := time.Now().Add(duration)
-	c.Data[key] = CacheValue{
-		Value:      value,
-		Expiration: expirationTime,
-	}
-}
-
-// a method is used to add new key-value pair to the cache.
-// It takes in a key, a value, and a time slot (by hour) representing the expiration time of the value
-// It first acquires a lock on the mutex to ensure thread safety, and then it adds the key-value pair to the map along with the expiration time.
-// Finally, it releases the lock.
-func (c *Cache) SetExpiredAtTime(key string, value interface{}, expiredTime time.Time) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-
-	c.Data[key] = CacheValue{
-		Value:      value,
-		Expiration: expiredTime,
-	}
-}
-
-// a method is used to retrieve a value from the cache by using a key
-// It first acquires a lock on the mutex to ensure thread safety.
-// Then checks if the cache contains a value for the given key and if that value has not expired.
-// If the value is still valid, it returns the value and a boolean value of `true` to indicate that a valid value was found.
-// If the value is not valid (means not yet cached), it returns `nil` and a boolean value of `false`.
-func (c *Cache) Get(key string) (interface{}, bool) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-
-	value, exists := c.Data[key]
-	if !exists {
-		c.logger.Debug("[go-goods] cache key was not found from cache", zap.String("key", key))
-		return nil, false
-	}
-	if time.Now().After(value.Expiration) {
-		c.logger.Debug("[go-goods] cache was expired",
-			zap.String("key", key),
-			zap.Time("expiration-time", value.Expiration),
-		)
-		delete(c.Data, key)
-		return nil, false
-	}
-	return value.Value, true
-}
-
-// Delete cache based on receiving cache key. If key is not valid, then Delete is no-op
-func (c *Cache) Delete(key string) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	delete(c.Data, key)
+// RegisterType registers the type of sample under name in the default codec of the cache,
+// so values of that type keep their type across Snapshot and Restore. See TypeCodec.Register for details.
+//
+// Example usage:
+//
+//	c.RegisterType("prices", []Price{})
+func (c *Cache) RegisterType(name string, sample interface{}) {
+	c.types.Register(name, sample)
 }
 
 // DeleteAll removes all cache entries that contain the specified key as a substring.
+//
+// Deprecated: DeleteAll scans every entry and also removes keys which only contain key in the middle.
+// Use DeletePrefix or InvalidateTag instead.
 func (c *Cache) DeleteAll(key string) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-
-	for k := range c.Data {
-		if strings.Contains(k, key) {
-			delete(c.Data, k)
-		}
-	}
+	c.DeleteFunc(func(k string) bool {
+		return strings.Contains(k, key)
+	})
 }
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -61,3 +62,97 @@ func BenchmarkCacheDeleteAll(b *testing.B) {
 		c.DeleteAll("user:")
 	}
 }
+
+func BenchmarkTypedGetHit(b *testing.B) {
+	logger := zap.NewNop()
+	c := NewTyped[string, string](logger)
+	c.SetExpiredAfterTimePeriod("key", "value", time.Minute)
+
+	for b.Loop() {
+		c.Get("key")
+	}
+}
+
+func BenchmarkCachePurgeExpired(b *testing.B) {
+	logger := zap.NewNop()
+	c := NewCache(logger)
+
+	for b.Loop() {
+		b.StopTimer()
+		for i := 0; i < 10_000; i++ {
+			c.SetExpiredAfterTimePeriod(fmt.Sprintf("user:%d", i), "value", -time.Second)
+		}
+		b.StartTimer()
+
+		c.purgeExpired()
+	}
+}
+
+func BenchmarkCacheGetOrLoadHit(b *testing.B) {
+	logger := zap.NewNop()
+	c := NewCache(logger)
+	c.SetExpiredAfterTimePeriod("key", "value", time.Minute)
+	loader := func(ctx context.Context) (interface{}, error) {
+		return "value", nil
+	}
+
+	for b.Loop() {
+		c.GetOrLoad(context.Background(), "key", time.Minute, loader)
+	}
+}
+
+func BenchmarkCacheDeletePrefix(b *testing.B) {
+	logger := zap.NewNop()
+	c := NewCache(logger)
+
+	// Prepare 100k keys, of which 100 match the prefix
+	for i := 0; i < 100_000; i++ {
+		c.SetExpiredAfterTimePeriod(fmt.Sprintf("user:%d", i), "value", time.Minute)
+	}
+
+	for b.Loop() {
+		b.StopTimer()
+		for i := 0; i < 100; i++ {
+			c.SetExpiredAfterTimePeriod(fmt.Sprintf("post:%d", i), "value", time.Minute)
+		}
+		b.StartTimer()
+
+		c.DeletePrefix("post:")
+	}
+}
+
+func BenchmarkCacheSetMaxBytes(b *testing.B) {
+	logger := zap.NewNop()
+	c := NewCache(logger, WithMaxBytes(1<<20))
+	value := make([]byte, 1024)
+
+	i := 0
+	for b.Loop() {
+		c.SetExpiredAfterTimePeriod(fmt.Sprintf("key:%d", i%10_000), value, time.Minute)
+		i++
+	}
+}
+
+func BenchmarkCacheAll(b *testing.B) {
+	c := NewCache(zap.NewNop())
+	for i := 0; i < 1000; i++ {
+		c.SetExpiredAfterTimePeriod(fmt.Sprintf("key:%d", i), i, time.Minute)
+	}
+
+	for b.Loop() {
+		for range c.All() {
+		}
+	}
+}
+
+func BenchmarkCacheSetMany(b *testing.B) {
+	c := NewCache(zap.NewNop())
+	values := make(map[string]interface{}, 100)
+	for i := 0; i < 100; i++ {
+		values[fmt.Sprintf("key:%d", i)] = i
+	}
+
+	for b.Loop() {
+		c.SetMany(values, time.Minute)
+	}
+}
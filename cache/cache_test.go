@@ -4,15 +4,18 @@ import (
 	"testing"
 	"time"
 
+	"github.com/AnhCaooo/go-goods/clock"
 	"go.uber.org/zap"
 )
 
-func setupTestCache(t *testing.T) *Cache {
+// setupTestCache returns a cache reading the time from a fake clock, so expirations can be checked exactly
+func setupTestCache(t *testing.T) (*Cache, *clock.Fake) {
 	logger, err := zap.NewDevelopment()
 	if err != nil {
 		t.Fatalf("failed to create logger: %v", err)
 	}
-	return NewCache(logger)
+	clk := clock.NewFake(time.Now())
+	return NewCache(logger, WithClock(clk)), clk
 }
 
 func TestNewCache(t *testing.T) {
@@ -22,14 +25,11 @@ func TestNewCache(t *testing.T) {
 	if cache == nil {
 		t.Error("NewCache() returned nil")
 	}
-	if cache.Data == nil {
-		t.Error("NewCache() did not initialize Data map")
-	}
 	if cache.logger == nil {
 		t.Error("NewCache() did not initialize logger")
 	}
-	if len(cache.Data) != 0 {
-		t.Error("NewCache() did not initialize empty Data map")
+	if cache.Len() != 0 {
+		t.Error("NewCache() did not initialize an empty cache")
 	}
 }
 
@@ -68,16 +68,14 @@ func TestSetExpiredAfterTimePeriod(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			cache := setupTestCache(t)
-			beforeSet := time.Now()
+			cache, clk := setupTestCache(t)
 			cache.SetExpiredAfterTimePeriod(test.key, test.value, test.duration)
-			afterSet := time.Now()
 
-			if _, exists := cache.Data[test.key]; !exists {
+			if _, exists := cache.data[test.key]; !exists {
 				t.Errorf("Key %q was not added to cache", test.key)
 			}
 
-			cacheValue := cache.Data[test.key]
+			cacheValue := cache.data[test.key]
 
 			// Use reflect.DeepEqual for complex types or direct comparison for primitives
 			if test.value != nil {
@@ -104,79 +102,76 @@ func TestSetExpiredAfterTimePeriod(t *testing.T) {
 				}
 			}
 
-			expectedMinExpiration := beforeSet.Add(test.duration)
-			expectedMaxExpiration := afterSet.Add(test.duration)
-			if cacheValue.Expiration.Before(expectedMinExpiration) ||
-				cacheValue.Expiration.After(expectedMaxExpiration.Add(100*time.Millisecond)) {
-				t.Errorf("Expiration time mismatch: got %v, expected between %v and %v",
-					cacheValue.Expiration, expectedMinExpiration, expectedMaxExpiration)
+			if expected := clk.Now().Add(test.duration); !cacheValue.Expiration.Equal(expected) {
+				t.Errorf("Expiration time mismatch: got %v, expected %v", cacheValue.Expiration, expected)
 			}
 		})
 	}
 }
 
 func TestSetExpiredAtTime(t *testing.T) {
-	futureTime := time.Now().Add(1 * time.Hour)
-	pastTime := time.Now().Add(-1 * time.Hour)
-	currentTime := time.Now()
-
 	tests := []struct {
-		name        string
-		key         string
-		value       interface{}
-		expiredTime time.Time
+		name          string
+		key           string
+		value         interface{}
+		expiresIn     time.Duration
+		expectedFound bool
 	}{
 		{
-			name:        "Set with future expiration",
-			key:         "key1",
-			value:       "testValue",
-			expiredTime: futureTime,
+			name:          "Set with future expiration",
+			key:           "key1",
+			value:         "testValue",
+			expiresIn:     1 * time.Hour,
+			expectedFound: true,
 		},
 		{
-			name:        "Set with past expiration",
-			key:         "key2",
-			value:       "expired",
-			expiredTime: pastTime,
+			name:          "Set with past expiration",
+			key:           "key2",
+			value:         "expired",
+			expiresIn:     -1 * time.Hour,
+			expectedFound: false,
 		},
 		{
-			name:        "Set with exact current time",
-			key:         "key3",
-			value:       42,
-			expiredTime: currentTime,
+			// a value expires after its expiration time, so it is still served at that exact time
+			name:          "Set with exact current time",
+			key:           "key3",
+			value:         42,
+			expiresIn:     0,
+			expectedFound: true,
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			cache := setupTestCache(t)
-			cache.SetExpiredAtTime(test.key, test.value, test.expiredTime)
+			cache, clk := setupTestCache(t)
+			expiredTime := clk.Now().Add(test.expiresIn)
+			cache.SetExpiredAtTime(test.key, test.value, expiredTime)
 
-			if _, exists := cache.Data[test.key]; !exists {
+			if _, exists := cache.data[test.key]; !exists {
 				t.Errorf("Key %q was not added to cache", test.key)
 			}
 
-			cacheValue := cache.Data[test.key]
+			cacheValue := cache.data[test.key]
 			if cacheValue.Value != test.value {
 				t.Errorf("Expected value %v, got %v", test.value, cacheValue.Value)
 			}
 
-			if cacheValue.Expiration != test.expiredTime {
-				t.Errorf("Expected expiration %v, got %v", test.expiredTime, cacheValue.Expiration)
+			if !cacheValue.Expiration.Equal(expiredTime) {
+				t.Errorf("Expected expiration %v, got %v", expiredTime, cacheValue.Expiration)
+			}
+			if _, found := cache.Peek(test.key); found != test.expectedFound {
+				t.Errorf("Expected found=%v, got %v", test.expectedFound, found)
 			}
 		})
 	}
 }
 
 func TestGet(t *testing.T) {
-	futureTime := time.Now().Add(1 * time.Hour)
-	pastTime := time.Now().Add(-1 * time.Hour)
-	nearFutureTime := time.Now().Add(30 * time.Minute)
-
 	tests := []struct {
 		name           string
 		key            string
 		value          interface{}
-		expiration     time.Time
+		expiresIn      time.Duration
 		expectedValue  interface{}
 		expectedExists bool
 	}{
@@ -184,7 +179,7 @@ func TestGet(t *testing.T) {
 			name:           "Get valid non-expired value",
 			key:            "key1",
 			value:          "testValue",
-			expiration:     futureTime,
+			expiresIn:      1 * time.Hour,
 			expectedValue:  "testValue",
 			expectedExists: true,
 		},
@@ -198,7 +193,7 @@ func TestGet(t *testing.T) {
 			name:           "Get expired value",
 			key:            "expiredKey",
 			value:          "oldValue",
-			expiration:     pastTime,
+			expiresIn:      -1 * time.Hour,
 			expectedValue:  nil,
 			expectedExists: false,
 		},
@@ -206,7 +201,7 @@ func TestGet(t *testing.T) {
 			name:           "Get with integer value",
 			key:            "intKey",
 			value:          123,
-			expiration:     nearFutureTime,
+			expiresIn:      30 * time.Minute,
 			expectedValue:  123,
 			expectedExists: true,
 		},
@@ -214,13 +209,10 @@ func TestGet(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			cache := setupTestCache(t)
+			cache, clk := setupTestCache(t)
 
 			if test.key != "nonexistent" {
-				cache.Data[test.key] = CacheValue{
-					Value:      test.value,
-					Expiration: test.expiration,
-				}
+				cache.SetExpiredAtTime(test.key, test.value, clk.Now().Add(test.expiresIn))
 			}
 
 			value, exists := cache.Get(test.key)
@@ -234,8 +226,8 @@ func TestGet(t *testing.T) {
 			}
 
 			// Verify expired values are deleted
-			if test.expiration.Before(time.Now()) && test.key != "nonexistent" {
-				if _, stillExists := cache.Data[test.key]; stillExists {
+			if test.expiresIn < 0 && test.key != "nonexistent" {
+				if _, stillExists := cache.data[test.key]; stillExists {
 					t.Errorf("Expired key %q should have been deleted", test.key)
 				}
 			}
@@ -268,27 +260,24 @@ func TestDelete(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			cache := setupTestCache(t)
+			cache, clk := setupTestCache(t)
 
 			// Add keys to cache
 			for _, key := range test.keysToAdd {
-				cache.Data[key] = CacheValue{
-					Value:      "value",
-					Expiration: time.Now().Add(1 * time.Hour),
-				}
+				cache.SetExpiredAtTime(key, "value", clk.Now().Add(1*time.Hour))
 			}
 
 			cache.Delete(test.keyToDelete)
 
 			// Check if key was deleted
-			if _, exists := cache.Data[test.keyToDelete]; exists {
+			if _, exists := cache.data[test.keyToDelete]; exists {
 				t.Errorf("Key %q should have been deleted", test.keyToDelete)
 			}
 
 			// Verify other keys remain
 			for _, key := range test.keysToAdd {
 				if key != test.keyToDelete {
-					if _, exists := cache.Data[key]; !exists {
+					if _, exists := cache.data[key]; !exists {
 						t.Errorf("Key %q should still exist", key)
 					}
 				}
@@ -338,14 +327,11 @@ func TestDeleteAll(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			cache := setupTestCache(t)
+			cache, clk := setupTestCache(t)
 
 			// Add keys to cache
 			for _, key := range test.keysToAdd {
-				cache.Data[key] = CacheValue{
-					Value:      "value",
-					Expiration: time.Now().Add(1 * time.Hour),
-				}
+				cache.SetExpiredAtTime(key, "value", clk.Now().Add(1*time.Hour))
 			}
 
 			cache.DeleteAll(test.substringToDelete)
@@ -353,7 +339,7 @@ func TestDeleteAll(t *testing.T) {
 			// Verify deleted keys are gone
 			for _, key := range test.keysToAdd {
 				if !contains(test.expectedRemaining, key) {
-					if _, exists := cache.Data[key]; exists {
+					if _, exists := cache.data[key]; exists {
 						t.Errorf("Key %q should have been deleted", key)
 					}
 				}
@@ -361,13 +347,13 @@ func TestDeleteAll(t *testing.T) {
 
 			// Verify remaining keys still exist
 			for _, key := range test.expectedRemaining {
-				if _, exists := cache.Data[key]; !exists {
+				if _, exists := cache.data[key]; !exists {
 					t.Errorf("Key %q should still exist", key)
 				}
 			}
 
-			if len(cache.Data) != len(test.expectedRemaining) {
-				t.Errorf("Expected %d keys remaining, got %d", len(test.expectedRemaining), len(cache.Data))
+			if cache.Len() != len(test.expectedRemaining) {
+				t.Errorf("Expected %d keys remaining, got %d", len(test.expectedRemaining), cache.Len())
 			}
 		})
 	}
@@ -0,0 +1,80 @@
+package cache
+
+// EvictionReason represents why an entry left the cache
+type EvictionReason int
+
+const (
+	ReasonExpired   EvictionReason = iota // ReasonExpired means the entry reached its expiration
+	ReasonDeleted                         // ReasonDeleted means the entry was removed by Delete
+	ReasonCapacity                        // ReasonCapacity means the entry was evicted to make room in a full cache
+	ReasonDeleteAll                       // ReasonDeleteAll means the entry was removed by DeleteAll or DeleteFunc
+)
+
+// String returns the name of the eviction reason
+func (r EvictionReason) String() string {
+	switch r {
+	case ReasonExpired:
+		return "expired"
+	case ReasonDeleted:
+		return "deleted"
+	case ReasonCapacity:
+		return "capacity"
+	case ReasonDeleteAll:
+		return "delete_all"
+	default:
+		return "unknown"
+	}
+}
+
+// EvictionCallback is called with the key, the value and the reason of an entry leaving the cache
+type EvictionCallback[K comparable, V any] func(key K, value V, reason EvictionReason)
+
+// removal is an entry that left the cache and still has to be reported to the callbacks
+type removal[K comparable, V any] struct {
+	key    K
+	value  V
+	reason EvictionReason
+}
+
+// OnEvict registers a callback which is called whenever an entry leaves the cache, whatever the reason.
+// Callbacks are called after the cache lock is released, so they may safely use the cache.
+// They run on the goroutine which removed the entry, e.g. the caller of Delete or the janitor, so they should return quickly.
+//
+// Example usage:
+//
+//	c.OnEvict(func(key string, conn *Conn, reason cache.EvictionReason) {
+//		conn.Close()
+//	})
+func (c *Typed[K, V]) OnEvict(callback EvictionCallback[K, V]) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.onEvict = append(c.onEvict, callback)
+}
+
+// OnExpire registers a callback which is called whenever an entry leaves the cache because it expired.
+// Callbacks are called after the cache lock is released, so they may safely use the cache.
+func (c *Typed[K, V]) OnExpire(callback EvictionCallback[K, V]) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.onExpire = append(c.onExpire, callback)
+}
+
+// unlock releases the write lock and then reports the entries removed while it was held to the callbacks
+func (c *Typed[K, V]) unlock() {
+	removed := c.removed
+	c.removed = nil
+	onEvict, onExpire := c.onEvict, c.onExpire
+	c.lock.Unlock()
+
+	for _, r := range removed {
+		for _, callback := range onEvict {
+			callback(r.key, r.value, r.reason)
+		}
+		if r.reason != ReasonExpired {
+			continue
+		}
+		for _, callback := range onExpire {
+			callback(r.key, r.value, r.reason)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type eviction struct {
+	key    string
+	value  interface{}
+	reason EvictionReason
+}
+
+// recordEvictions registers callbacks on c and returns functions reading what they received
+func recordEvictions(c *Cache) (evicted func() []eviction, expired func() []eviction) {
+	var lock sync.Mutex
+	var onEvict, onExpire []eviction
+	c.OnEvict(func(key string, value interface{}, reason EvictionReason) {
+		lock.Lock()
+		defer lock.Unlock()
+		onEvict = append(onEvict, eviction{key, value, reason})
+	})
+	c.OnExpire(func(key string, value interface{}, reason EvictionReason) {
+		lock.Lock()
+		defer lock.Unlock()
+		onExpire = append(onExpire, eviction{key, value, reason})
+	})
+	read := func(events *[]eviction) func() []eviction {
+		return func() []eviction {
+			lock.Lock()
+			defer lock.Unlock()
+			return append([]eviction(nil), *events...)
+		}
+	}
+	return read(&onEvict), read(&onExpire)
+}
+
+func TestEvictionCallbacks(t *testing.T) {
+	tests := []struct {
+		name            string
+		options         []Option
+		act             func(c *Cache)
+		expectedEvicted []eviction
+		expectedExpired []eviction
+	}{
+		{
+			name: "Expired on Get",
+			act: func(c *Cache) {
+				c.SetExpiredAfterTimePeriod("key", "value", -time.Second)
+				c.Get("key")
+			},
+			expectedEvicted: []eviction{{"key", "value", ReasonExpired}},
+			expectedExpired: []eviction{{"key", "value", ReasonExpired}},
+		},
+		{
+			name: "Expired by janitor",
+			act: func(c *Cache) {
+				c.SetExpiredAfterTimePeriod("key", "value", -time.Second)
+				c.purgeExpired()
+			},
+			expectedEvicted: []eviction{{"key", "value", ReasonExpired}},
+			expectedExpired: []eviction{{"key", "value", ReasonExpired}},
+		},
+		{
+			name: "Deleted",
+			act: func(c *Cache) {
+				c.SetExpiredAfterTimePeriod("key", "value", time.Minute)
+				c.Delete("key")
+			},
+			expectedEvicted: []eviction{{"key", "value", ReasonDeleted}},
+		},
+		{
+			name: "Delete non-existent key",
+			act: func(c *Cache) {
+				c.Delete("key")
+			},
+		},
+		{
+			name:    "Evicted for capacity",
+			options: []Option{WithMaxEntries(1)},
+			act: func(c *Cache) {
+				c.SetExpiredAfterTimePeriod("old", 1, time.Minute)
+				c.SetExpiredAfterTimePeriod("new", 2, time.Minute)
+			},
+			expectedEvicted: []eviction{{"old", 1, ReasonCapacity}},
+		},
+		{
+			name: "DeleteAll",
+			act: func(c *Cache) {
+				c.SetExpiredAfterTimePeriod("user:1", "alice", time.Minute)
+				c.SetExpiredAfterTimePeriod("post:1", "hello", time.Minute)
+				c.DeleteAll("user")
+			},
+			expectedEvicted: []eviction{{"user:1", "alice", ReasonDeleteAll}},
+		},
+		{
+			name: "Overwrite is not an eviction",
+			act: func(c *Cache) {
+				c.SetExpiredAfterTimePeriod("key", "old", time.Minute)
+				c.SetExpiredAfterTimePeriod("key", "new", time.Minute)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := NewCache(zap.NewNop(), test.options...)
+			evicted, expired := recordEvictions(c)
+
+			test.act(c)
+
+			assertEvictions(t, "OnEvict", evicted(), test.expectedEvicted)
+			assertEvictions(t, "OnExpire", expired(), test.expectedExpired)
+		})
+	}
+}
+
+func TestEvictionCallbackCanUseCache(t *testing.T) {
+	c := NewCache(zap.NewNop())
+	c.OnEvict(func(key string, value interface{}, reason EvictionReason) {
+		// warming a replacement from inside the callback must not deadlock
+		if _, found := c.Get("replacement"); !found {
+			c.SetExpiredAfterTimePeriod("replacement", value, time.Minute)
+		}
+	})
+
+	c.SetExpiredAfterTimePeriod("key", "value", time.Minute)
+
+	done := make(chan struct{})
+	go func() {
+		c.Delete("key")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Callback using the cache deadlocked")
+	}
+
+	if value, found := c.Get("replacement"); !found || value != "value" {
+		t.Errorf("Expected (value, true), got (%v, %v)", value, found)
+	}
+}
+
+func assertEvictions(t *testing.T, hook string, got []eviction, expected []eviction) {
+	t.Helper()
+	if len(got) != len(expected) {
+		t.Fatalf("%s: expected %d calls, got %d: %v", hook, len(expected), len(got), got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("%s: expected %v, got %v", hook, expected[i], got[i])
+		}
+	}
+}
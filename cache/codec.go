@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Codec converts cached values to bytes and back, so they can be written to a snapshot or a shared store
+type Codec[V any] interface {
+	Encode(value V) ([]byte, error)
+	Decode(data []byte) (V, error)
+}
+
+// JSONCodec encodes values as JSON. It is the default codec of Typed,
+// where the concrete type V is known and decoding restores it.
+type JSONCodec[V any] struct{}
+
+// Encode returns the JSON encoding of value
+func (JSONCodec[V]) Encode(value V) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode cache value: %w", err)
+	}
+	return data, nil
+}
+
+// Decode parses the JSON encoded value
+func (JSONCodec[V]) Decode(data []byte) (V, error) {
+	var value V
+	if err := json.Unmarshal(data, &value); err != nil {
+		return value, fmt.Errorf("failed to decode cache value: %w", err)
+	}
+	return value, nil
+}
+
+// TypeCodec encodes interface{} values as JSON together with the name of their registered type,
+// so that decoding restores the original type instead of generic maps and float64.
+// It is the default codec of Cache. Common built-in types are registered by NewTypeCodec.
+//
+// Example usage:
+//
+//	codec := cache.NewTypeCodec()
+//	codec.Register("prices", []Price{})
+type TypeCodec struct {
+	lock   sync.RWMutex
+	byName map[string]reflect.Type
+	byType map[reflect.Type]string
+}
+
+type typedValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// NewTypeCodec returns a TypeCodec with the common built-in types already registered
+func NewTypeCodec() *TypeCodec {
+	codec := &TypeCodec{
+		byName: make(map[string]reflect.Type),
+		byType: make(map[reflect.Type]string),
+	}
+	for name, sample := range map[string]interface{}{
+		"string":   "",
+		"bool":     false,
+		"int":      0,
+		"int64":    int64(0),
+		"float64":  float64(0),
+		"bytes":    []byte(nil),
+		"strings":  []string(nil),
+		"json.map": map[string]interface{}(nil),
+	} {
+		codec.Register(name, sample)
+	}
+	return codec
+}
+
+// Register associates a name with the type of sample. The name is written next to every encoded value of that type,
+// so it must stay the same between the service version writing and the one reading the data.
+// Registering a name again replaces its type.
+func (c *TypeCodec) Register(name string, sample interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	t := reflect.TypeOf(sample)
+	c.byName[name] = t
+	c.byType[t] = name
+}
+
+// Encode returns the JSON encoding of value with the name of its type. The type must be registered.
+func (c *TypeCodec) Encode(value interface{}) ([]byte, error) {
+	c.lock.RLock()
+	name, ok := c.byType[reflect.TypeOf(value)]
+	c.lock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("failed to encode cache value: type %T is not registered", value)
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode cache value: %w", err)
+	}
+	data, err := json.Marshal(typedValue{Type: name, Value: raw})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode cache value: %w", err)
+	}
+	return data, nil
+}
+
+// Decode parses a value produced by Encode into a new value of its registered type
+func (c *TypeCodec) Decode(data []byte) (interface{}, error) {
+	var encoded typedValue
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return nil, fmt.Errorf("failed to decode cache value: %w", err)
+	}
+
+	c.lock.RLock()
+	t, ok := c.byName[encoded.Type]
+	c.lock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("failed to decode cache value: type %q is not registered", encoded.Type)
+	}
+
+	value := reflect.New(t)
+	if err := json.Unmarshal(encoded.Value, value.Interface()); err != nil {
+		return nil, fmt.Errorf("failed to decode cache value of type %q: %w", encoded.Type, err)
+	}
+	return value.Elem().Interface(), nil
+}
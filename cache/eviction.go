@@ -0,0 +1,359 @@
+package cache
+
+import (
+	"container/list"
+	"hash/maphash"
+)
+
+// EvictionPolicy represents the strategy used to choose which entry leaves a full cache
+type EvictionPolicy int
+
+const (
+	LRU     EvictionPolicy = iota // LRU evicts the least recently used entry
+	LFU                           // LFU evicts the least frequently used entry, ties are broken by recency
+	TinyLFU                       // TinyLFU is W-TinyLFU: a small LRU window in front of a frequency-gated segmented LRU
+)
+
+// String returns the name of the eviction policy
+func (p EvictionPolicy) String() string {
+	switch p {
+	case LRU:
+		return "lru"
+	case LFU:
+		return "lfu"
+	case TinyLFU:
+		return "tinylfu"
+	default:
+		return "unknown"
+	}
+}
+
+// evictor keeps track of key usage and chooses victims when the cache is over capacity.
+// Implementations are not thread-safe; the cache calls them while holding its lock.
+type evictor[K comparable] interface {
+	// add records a key that was newly inserted into the cache
+	add(key K)
+	// access records a read or an overwrite of an existing key
+	access(key K)
+	// remove forgets a key that left the cache for any other reason than eviction
+	remove(key K)
+	// victim chooses a key to evict and forgets it. It returns false if no key is tracked.
+	// It is called before a new key is added to a full cache.
+	victim() (K, bool)
+}
+
+// newEvictor returns the evictor implementing the given policy for a cache holding up to capacity entries
+func newEvictor[K comparable](policy EvictionPolicy, capacity int) evictor[K] {
+	switch policy {
+	case LFU:
+		return newLFU[K]()
+	case TinyLFU:
+		return newTinyLFU[K](capacity)
+	default:
+		return newLRU[K]()
+	}
+}
+
+// lruList is a recency list: the front is the most recently used key and the back is the least recently used one
+type lruList[K comparable] struct {
+	order *list.List
+	items map[K]*list.Element
+}
+
+func newLRU[K comparable]() *lruList[K] {
+	return &lruList[K]{
+		order: list.New(),
+		items: make(map[K]*list.Element),
+	}
+}
+
+func (l *lruList[K]) add(key K) {
+	if element, ok := l.items[key]; ok {
+		l.order.MoveToFront(element)
+		return
+	}
+	l.items[key] = l.order.PushFront(key)
+}
+
+func (l *lruList[K]) access(key K) {
+	if element, ok := l.items[key]; ok {
+		l.order.MoveToFront(element)
+	}
+}
+
+func (l *lruList[K]) remove(key K) {
+	if element, ok := l.items[key]; ok {
+		l.order.Remove(element)
+		delete(l.items, key)
+	}
+}
+
+func (l *lruList[K]) victim() (K, bool) {
+	key, ok := l.back()
+	if ok {
+		l.remove(key)
+	}
+	return key, ok
+}
+
+func (l *lruList[K]) back() (K, bool) {
+	element := l.order.Back()
+	if element == nil {
+		var zero K
+		return zero, false
+	}
+	return element.Value.(K), true
+}
+
+func (l *lruList[K]) contains(key K) bool {
+	_, ok := l.items[key]
+	return ok
+}
+
+func (l *lruList[K]) len() int {
+	return l.order.Len()
+}
+
+// lfu is an O(1) LFU implementation.
+// It keeps a list of frequency buckets in ascending order, each bucket holding its keys in recency order.
+type lfu[K comparable] struct {
+	buckets *list.List // of *lfuBucket[K]
+	items   map[K]*lfuItem[K]
+}
+
+type lfuBucket[K comparable] struct {
+	frequency int
+	keys      *list.List // of K, front is the most recently used
+}
+
+type lfuItem[K comparable] struct {
+	bucket *list.Element // element of lfu.buckets
+	key    *list.Element // element of lfuBucket.keys
+}
+
+func newLFU[K comparable]() *lfu[K] {
+	return &lfu[K]{
+		buckets: list.New(),
+		items:   make(map[K]*lfuItem[K]),
+	}
+}
+
+func (l *lfu[K]) add(key K) {
+	if _, ok := l.items[key]; ok {
+		l.access(key)
+		return
+	}
+	first := l.buckets.Front()
+	if first == nil || first.Value.(*lfuBucket[K]).frequency != 1 {
+		first = l.buckets.PushFront(&lfuBucket[K]{frequency: 1, keys: list.New()})
+	}
+	l.items[key] = &lfuItem[K]{
+		bucket: first,
+		key:    first.Value.(*lfuBucket[K]).keys.PushFront(key),
+	}
+}
+
+func (l *lfu[K]) access(key K) {
+	item, ok := l.items[key]
+	if !ok {
+		return
+	}
+	current := item.bucket.Value.(*lfuBucket[K])
+	next := item.bucket.Next()
+	if next == nil || next.Value.(*lfuBucket[K]).frequency != current.frequency+1 {
+		next = l.buckets.InsertAfter(&lfuBucket[K]{frequency: current.frequency + 1, keys: list.New()}, item.bucket)
+	}
+	l.detach(item)
+	item.bucket = next
+	item.key = next.Value.(*lfuBucket[K]).keys.PushFront(key)
+}
+
+func (l *lfu[K]) remove(key K) {
+	if item, ok := l.items[key]; ok {
+		l.detach(item)
+		delete(l.items, key)
+	}
+}
+
+func (l *lfu[K]) victim() (K, bool) {
+	first := l.buckets.Front()
+	if first == nil {
+		var zero K
+		return zero, false
+	}
+	key := first.Value.(*lfuBucket[K]).keys.Back().Value.(K)
+	l.remove(key)
+	return key, true
+}
+
+// detach unlinks an item from its bucket and drops the bucket once it is empty
+func (l *lfu[K]) detach(item *lfuItem[K]) {
+	bucket := item.bucket.Value.(*lfuBucket[K])
+	bucket.keys.Remove(item.key)
+	if bucket.keys.Len() == 0 {
+		l.buckets.Remove(item.bucket)
+	}
+}
+
+// tinyLFU implements W-TinyLFU.
+// New keys enter a small LRU window. Keys leaving the window compete with the victim of the main segmented LRU
+// and only the one with the higher estimated frequency is kept, so one-off scans cannot flush hot keys.
+type tinyLFU[K comparable] struct {
+	window     *lruList[K]
+	probation  *lruList[K]
+	protected  *lruList[K]
+	windowCap  int
+	mainCap    int
+	protectCap int
+	sketch     *countMinSketch[K]
+}
+
+func newTinyLFU[K comparable](capacity int) *tinyLFU[K] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	windowCap := max(1, capacity/100)
+	mainCap := capacity - windowCap
+	return &tinyLFU[K]{
+		window:     newLRU[K](),
+		probation:  newLRU[K](),
+		protected:  newLRU[K](),
+		windowCap:  windowCap,
+		mainCap:    mainCap,
+		protectCap: mainCap * 8 / 10,
+		sketch:     newCountMinSketch[K](capacity),
+	}
+}
+
+func (t *tinyLFU[K]) add(key K) {
+	if t.window.contains(key) || t.probation.contains(key) || t.protected.contains(key) {
+		t.access(key)
+		return
+	}
+	t.sketch.increment(key)
+	t.window.add(key)
+}
+
+func (t *tinyLFU[K]) access(key K) {
+	t.sketch.increment(key)
+	switch {
+	case t.window.contains(key):
+		t.window.access(key)
+	case t.probation.contains(key):
+		// promote to the protected segment, demoting its least recently used key when it is full
+		t.probation.remove(key)
+		t.protected.add(key)
+		if t.protected.len() > t.protectCap {
+			if demoted, ok := t.protected.victim(); ok {
+				t.probation.add(demoted)
+			}
+		}
+	case t.protected.contains(key):
+		t.protected.access(key)
+	}
+}
+
+func (t *tinyLFU[K]) remove(key K) {
+	t.window.remove(key)
+	t.probation.remove(key)
+	t.protected.remove(key)
+}
+
+func (t *tinyLFU[K]) victim() (K, bool) {
+	// the new key is about to enter the window, so a full window has to hand its oldest key over to the main segments
+	for t.window.len() >= t.windowCap {
+		candidate, _ := t.window.victim()
+		if t.probation.len()+t.protected.len() < t.mainCap {
+			t.probation.add(candidate)
+			continue
+		}
+
+		mainVictim, ok := t.probation.back()
+		if !ok {
+			mainVictim, ok = t.protected.back()
+		}
+		if !ok {
+			return candidate, true
+		}
+		if t.sketch.estimate(candidate) > t.sketch.estimate(mainVictim) {
+			t.remove(mainVictim)
+			t.probation.add(candidate)
+			return mainVictim, true
+		}
+		return candidate, true
+	}
+
+	for _, segment := range []*lruList[K]{t.probation, t.protected, t.window} {
+		if key, ok := segment.victim(); ok {
+			return key, true
+		}
+	}
+	var zero K
+	return zero, false
+}
+
+// countMinSketch estimates how often keys were accessed, using 4 rows of saturating 8-bit counters.
+// All counters are halved periodically so that the estimation favours recent popularity.
+type countMinSketch[K comparable] struct {
+	rows       [4][]uint8
+	mask       uint64
+	seed       maphash.Seed
+	additions  int
+	resetAfter int
+}
+
+func newCountMinSketch[K comparable](capacity int) *countMinSketch[K] {
+	// a wider sketch than the capacity keeps collisions between hot and one-off keys rare
+	width := 16
+	for width < 8*capacity {
+		width <<= 1
+	}
+	s := &countMinSketch[K]{
+		mask:       uint64(width - 1),
+		seed:       maphash.MakeSeed(),
+		resetAfter: 10 * width,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+func (s *countMinSketch[K]) increment(key K) {
+	hash := maphash.Comparable(s.seed, key)
+	for i := range s.rows {
+		index := s.index(hash, i)
+		if s.rows[i][index] < 255 {
+			s.rows[i][index]++
+		}
+	}
+	s.additions++
+	if s.additions >= s.resetAfter {
+		s.reset()
+	}
+}
+
+func (s *countMinSketch[K]) estimate(key K) uint8 {
+	hash := maphash.Comparable(s.seed, key)
+	estimate := uint8(255)
+	for i := range s.rows {
+		estimate = min(estimate, s.rows[i][s.index(hash, i)])
+	}
+	return estimate
+}
+
+func (s *countMinSketch[K]) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions /= 2
+}
+
+// index derives the counter position of the i-th row from a single 64-bit hash
+func (s *countMinSketch[K]) index(hash uint64, row int) uint64 {
+	h := (hash ^ uint64(row+1)*0x9e3779b97f4a7c15) * 0xbf58476d1ce4e5b9
+	h ^= h >> 31
+	return h & s.mask
+}
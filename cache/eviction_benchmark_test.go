@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func benchmarkBoundedSet(b *testing.B, policy EvictionPolicy) {
+	logger := zap.NewNop()
+	c := NewCache(logger, WithMaxEntries(10_000), WithEvictionPolicy(policy))
+
+	keys := make([]string, 100_000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key:%d", i)
+	}
+
+	i := 0
+	for b.Loop() {
+		c.SetExpiredAfterTimePeriod(keys[i%len(keys)], "value", time.Minute)
+		i++
+	}
+}
+
+func benchmarkBoundedGet(b *testing.B, policy EvictionPolicy) {
+	logger := zap.NewNop()
+	c := NewCache(logger, WithMaxEntries(10_000), WithEvictionPolicy(policy))
+
+	keys := make([]string, 20_000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key:%d", i)
+	}
+	// skewed access: low indexes are requested much more often
+	zipf := rand.NewZipf(rand.New(rand.NewPCG(1, 2)), 1.1, 1, uint64(len(keys)-1))
+
+	for b.Loop() {
+		key := keys[zipf.Uint64()]
+		if _, found := c.Get(key); !found {
+			c.SetExpiredAfterTimePeriod(key, "value", time.Minute)
+		}
+	}
+}
+
+func BenchmarkCacheSetLRU(b *testing.B)     { benchmarkBoundedSet(b, LRU) }
+func BenchmarkCacheSetLFU(b *testing.B)     { benchmarkBoundedSet(b, LFU) }
+func BenchmarkCacheSetTinyLFU(b *testing.B) { benchmarkBoundedSet(b, TinyLFU) }
+
+func BenchmarkCacheGetLRU(b *testing.B)     { benchmarkBoundedGet(b, LRU) }
+func BenchmarkCacheGetLFU(b *testing.B)     { benchmarkBoundedGet(b, LFU) }
+func BenchmarkCacheGetTinyLFU(b *testing.B) { benchmarkBoundedGet(b, TinyLFU) }
@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestMaxEntries(t *testing.T) {
+	for _, policy := range []EvictionPolicy{LRU, LFU, TinyLFU} {
+		t.Run(policy.String(), func(t *testing.T) {
+			c := NewTyped[int, int](zap.NewNop(), WithMaxEntries(100), WithEvictionPolicy(policy))
+			for i := 0; i < 1000; i++ {
+				c.SetExpiredAfterTimePeriod(i, i, time.Minute)
+			}
+			if len(c.data) != 100 {
+				t.Errorf("Expected 100 entries, got %d", len(c.data))
+			}
+		})
+	}
+}
+
+func TestUnboundedByDefault(t *testing.T) {
+	c := NewTyped[int, int](zap.NewNop())
+	for i := 0; i < 1000; i++ {
+		c.SetExpiredAfterTimePeriod(i, i, time.Minute)
+	}
+	if len(c.data) != 1000 {
+		t.Errorf("Expected 1000 entries, got %d", len(c.data))
+	}
+	if c.evictor != nil {
+		t.Error("Unbounded cache should not track usage")
+	}
+}
+
+func TestLRUEviction(t *testing.T) {
+	c := NewTyped[string, int](zap.NewNop(), WithMaxEntries(3), WithEvictionPolicy(LRU))
+	c.SetExpiredAfterTimePeriod("a", 1, time.Minute)
+	c.SetExpiredAfterTimePeriod("b", 2, time.Minute)
+	c.SetExpiredAfterTimePeriod("c", 3, time.Minute)
+
+	// "a" becomes the most recently used, so "b" is the least recently used
+	c.Get("a")
+	c.SetExpiredAfterTimePeriod("d", 4, time.Minute)
+
+	assertKeys(t, c, []string{"a", "c", "d"}, []string{"b"})
+}
+
+func TestLFUEviction(t *testing.T) {
+	c := NewTyped[string, int](zap.NewNop(), WithMaxEntries(3), WithEvictionPolicy(LFU))
+	c.SetExpiredAfterTimePeriod("a", 1, time.Minute)
+	c.SetExpiredAfterTimePeriod("b", 2, time.Minute)
+	c.SetExpiredAfterTimePeriod("c", 3, time.Minute)
+
+	c.Get("a")
+	c.Get("a")
+	c.Get("b")
+	c.Get("c")
+	c.Get("c")
+	c.SetExpiredAfterTimePeriod("d", 4, time.Minute)
+
+	assertKeys(t, c, []string{"a", "c", "d"}, []string{"b"})
+
+	// ties are broken by recency: "d" is the only key used once
+	c.SetExpiredAfterTimePeriod("e", 5, time.Minute)
+	assertKeys(t, c, []string{"a", "c", "e"}, []string{"d"})
+}
+
+func TestTinyLFUKeepsHotKeysDuringScan(t *testing.T) {
+	c := NewTyped[string, int](zap.NewNop(), WithMaxEntries(100), WithEvictionPolicy(TinyLFU))
+
+	hot := make([]string, 50)
+	for i := range hot {
+		hot[i] = fmt.Sprintf("hot:%d", i)
+		c.SetExpiredAfterTimePeriod(hot[i], i, time.Minute)
+	}
+	for round := 0; round < 5; round++ {
+		for _, key := range hot {
+			c.Get(key)
+		}
+	}
+
+	// a one-off scan over many keys must not flush the frequently used ones
+	for i := 0; i < 1000; i++ {
+		c.SetExpiredAfterTimePeriod(fmt.Sprintf("scan:%d", i), i, time.Minute)
+	}
+
+	kept := 0
+	for _, key := range hot {
+		if _, exists := c.data[key]; exists {
+			kept++
+		}
+	}
+	if kept < len(hot)*9/10 {
+		t.Errorf("Expected most hot keys to survive the scan, kept %d of %d", kept, len(hot))
+	}
+	if len(c.data) != 100 {
+		t.Errorf("Expected 100 entries, got %d", len(c.data))
+	}
+}
+
+func TestEvictorForgetsDeletedKeys(t *testing.T) {
+	for _, policy := range []EvictionPolicy{LRU, LFU, TinyLFU} {
+		t.Run(policy.String(), func(t *testing.T) {
+			c := NewTyped[string, int](zap.NewNop(), WithMaxEntries(2), WithEvictionPolicy(policy))
+			c.SetExpiredAfterTimePeriod("a", 1, time.Minute)
+			c.SetExpiredAfterTimePeriod("b", 2, time.Minute)
+			c.Delete("a")
+			c.SetExpiredAfterTimePeriod("c", 3, time.Minute)
+
+			// deleting made room, so nothing had to be evicted
+			assertKeys(t, c, []string{"b", "c"}, []string{"a"})
+		})
+	}
+}
+
+func assertKeys[V any](t *testing.T, c *Typed[string, V], present []string, absent []string) {
+	t.Helper()
+	for _, key := range present {
+		if _, exists := c.data[key]; !exists {
+			t.Errorf("Key %q should be in cache", key)
+		}
+	}
+	for _, key := range absent {
+		if _, exists := c.data[key]; exists {
+			t.Errorf("Key %q should not be in cache", key)
+		}
+	}
+}
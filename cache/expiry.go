@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"container/heap"
+	"time"
+)
+
+// expiryQueue is a min-heap of keys ordered by expiration time.
+// It lets the janitor find expired entries without scanning the whole cache.
+// It is not thread-safe; the cache calls it while holding its lock.
+type expiryQueue[K comparable] struct {
+	items []expiryItem[K]
+	index map[K]int // position of each key in items
+}
+
+type expiryItem[K comparable] struct {
+	key        K
+	expiration time.Time
+}
+
+func newExpiryQueue[K comparable]() *expiryQueue[K] {
+	return &expiryQueue[K]{
+		index: make(map[K]int),
+	}
+}
+
+// set tracks the key with the given expiration, updating its position if it is already tracked
+func (q *expiryQueue[K]) set(key K, expiration time.Time) {
+	if i, ok := q.index[key]; ok {
+		q.items[i].expiration = expiration
+		heap.Fix(q, i)
+		return
+	}
+	heap.Push(q, expiryItem[K]{key: key, expiration: expiration})
+}
+
+// remove stops tracking the key. It is a no-op if the key is not tracked.
+func (q *expiryQueue[K]) remove(key K) {
+	if i, ok := q.index[key]; ok {
+		heap.Remove(q, i)
+	}
+}
+
+// peek returns the key that expires first
+func (q *expiryQueue[K]) peek() (expiryItem[K], bool) {
+	if len(q.items) == 0 {
+		return expiryItem[K]{}, false
+	}
+	return q.items[0], true
+}
+
+// heap.Interface implementation. Use set, remove and peek instead of calling these directly.
+
+func (q *expiryQueue[K]) Len() int { return len(q.items) }
+
+func (q *expiryQueue[K]) Less(i, j int) bool {
+	return q.items[i].expiration.Before(q.items[j].expiration)
+}
+
+func (q *expiryQueue[K]) Swap(i, j int) {
+	q.items[i], q.items[j] = q.items[j], q.items[i]
+	q.index[q.items[i].key] = i
+	q.index[q.items[j].key] = j
+}
+
+func (q *expiryQueue[K]) Push(x any) {
+	item := x.(expiryItem[K])
+	q.index[item.key] = len(q.items)
+	q.items = append(q.items, item)
+}
+
+func (q *expiryQueue[K]) Pop() any {
+	last := len(q.items) - 1
+	item := q.items[last]
+	q.items = q.items[:last]
+	delete(q.index, item.key)
+	return item
+}
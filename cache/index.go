@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"math/rand/v2"
+	"strings"
+)
+
+// prefixIndexMaxLevel bounds the height of the skip list, which comfortably indexes millions of keys
+const prefixIndexMaxLevel = 24
+
+// prefixIndex keeps string keys sorted in a skip list,
+// so all keys sharing a prefix are found in O(log n + matches) instead of scanning the whole cache.
+// It is not thread-safe; the cache calls it while holding its lock.
+type prefixIndex struct {
+	head  *prefixNode
+	level int
+}
+
+type prefixNode struct {
+	key  string
+	next []*prefixNode
+}
+
+func newPrefixIndex() *prefixIndex {
+	return &prefixIndex{
+		head:  &prefixNode{next: make([]*prefixNode, prefixIndexMaxLevel)},
+		level: 1,
+	}
+}
+
+// insert adds the key to the index. It is a no-op if the key is already indexed.
+func (p *prefixIndex) insert(key string) {
+	var update [prefixIndexMaxLevel]*prefixNode
+	node := p.head
+	for level := p.level - 1; level >= 0; level-- {
+		for node.next[level] != nil && node.next[level].key < key {
+			node = node.next[level]
+		}
+		update[level] = node
+	}
+	if next := node.next[0]; next != nil && next.key == key {
+		return
+	}
+
+	level := randomLevel()
+	for ; p.level < level; p.level++ {
+		update[p.level] = p.head
+	}
+	inserted := &prefixNode{key: key, next: make([]*prefixNode, level)}
+	for i := 0; i < level; i++ {
+		inserted.next[i] = update[i].next[i]
+		update[i].next[i] = inserted
+	}
+}
+
+// delete removes the key from the index. It is a no-op if the key is not indexed.
+func (p *prefixIndex) delete(key string) {
+	var update [prefixIndexMaxLevel]*prefixNode
+	node := p.head
+	for level := p.level - 1; level >= 0; level-- {
+		for node.next[level] != nil && node.next[level].key < key {
+			node = node.next[level]
+		}
+		update[level] = node
+	}
+	deleted := node.next[0]
+	if deleted == nil || deleted.key != key {
+		return
+	}
+
+	for i := range deleted.next {
+		update[i].next[i] = deleted.next[i]
+	}
+	for p.level > 1 && p.head.next[p.level-1] == nil {
+		p.level--
+	}
+}
+
+// keysWithPrefix returns all indexed keys starting with prefix, in ascending order
+func (p *prefixIndex) keysWithPrefix(prefix string) []string {
+	node := p.head
+	for level := p.level - 1; level >= 0; level-- {
+		for node.next[level] != nil && node.next[level].key < prefix {
+			node = node.next[level]
+		}
+	}
+
+	var keys []string
+	for node = node.next[0]; node != nil && strings.HasPrefix(node.key, prefix); node = node.next[0] {
+		keys = append(keys, node.key)
+	}
+	return keys
+}
+
+// randomLevel returns the height of a new node: each additional level has a probability of 1/4
+func randomLevel() int {
+	level := 1
+	for level < prefixIndexMaxLevel && rand.IntN(4) == 0 {
+		level++
+	}
+	return level
+}
@@ -0,0 +1,168 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// publishTimeout bounds how long a delete waits for the invalidation to be published to the other replicas
+const publishTimeout = 5 * time.Second
+
+// InvalidationKind tells which entries an Invalidation removes
+type InvalidationKind string
+
+const (
+	InvalidationKey     InvalidationKind = "key"     // InvalidationKey removes the entry whose key is Value
+	InvalidationPrefix  InvalidationKind = "prefix"  // InvalidationPrefix removes the entries whose key starts with Value
+	InvalidationPattern InvalidationKind = "pattern" // InvalidationPattern removes the entries whose key contains Value, like DeleteAll
+	InvalidationTag     InvalidationKind = "tag"     // InvalidationTag removes the entries tagged with Value
+)
+
+// Invalidation is the message sent between replicas when entries are removed from one of them.
+// Origin identifies the replica which sent it, so it can ignore its own message when the transport echoes it back.
+type Invalidation struct {
+	Origin string           `json:"origin"`
+	Kind   InvalidationKind `json:"kind"`
+	Value  string           `json:"value"`
+}
+
+// Transport delivers invalidations between the replicas of a service
+type Transport interface {
+	// Publish sends the invalidation to the other replicas
+	Publish(ctx context.Context, message Invalidation) error
+	// Subscribe registers a handler called for every invalidation received
+	Subscribe(handler func(message Invalidation))
+	// Close stops receiving invalidations and releases the resources of the transport
+	Close() error
+}
+
+// ReplicatedCache is a local Cache whose deletes are propagated to the caches of the other replicas through a Transport.
+// Values are still cached privately by every replica; only removals are shared.
+// Publishing happens synchronously after the local removal, and failures are logged because the removal already happened locally.
+type ReplicatedCache struct {
+	*Cache
+	id        string
+	logger    *zap.Logger
+	transport Transport
+}
+
+// NewReplicatedCache returns c wrapped so that its deletes are published through transport,
+// and invalidations published by other replicas are applied to c.
+//
+// Example usage:
+//
+//	transport, err := cache.NewHTTPTransport(logger, cache.HTTPTransportConfig{Peers: peerURLs, Secret: secret})
+//	if err != nil {
+//		return err
+//	}
+//	router.Handle("/internal/cache/invalidate", transport)
+//	prices := cache.NewReplicatedCache(logger, cache.NewCache(logger), transport)
+//	defer prices.Close()
+//	prices.Delete(today) // removed on every replica
+func NewReplicatedCache(logger *zap.Logger, c *Cache, transport Transport) *ReplicatedCache {
+	r := &ReplicatedCache{
+		Cache:     c,
+		id:        newOriginID(),
+		logger:    logger,
+		transport: transport,
+	}
+	transport.Subscribe(r.apply)
+	return r
+}
+
+// ID returns the origin ID written to the invalidations published by this replica
+func (r *ReplicatedCache) ID() string {
+	return r.id
+}
+
+// Delete removes the entry of key on this and every other replica
+func (r *ReplicatedCache) Delete(key string) {
+	r.Cache.Delete(key)
+	r.publish(InvalidationKey, key)
+}
+
+// DeleteMany removes the entries of keys on this and every other replica
+func (r *ReplicatedCache) DeleteMany(keys ...string) {
+	r.Cache.DeleteMany(keys...)
+	for _, key := range keys {
+		r.publish(InvalidationKey, key)
+	}
+}
+
+// DeletePrefix removes the entries whose key starts with prefix on this and every other replica
+func (r *ReplicatedCache) DeletePrefix(prefix string) {
+	r.Cache.DeletePrefix(prefix)
+	r.publish(InvalidationPrefix, prefix)
+}
+
+// DeleteAll removes the entries whose key contains keyPattern on this and every other replica
+//
+// Deprecated: use DeletePrefix or InvalidateTag, which do not scan every entry.
+func (r *ReplicatedCache) DeleteAll(keyPattern string) {
+	r.Cache.DeleteAll(keyPattern)
+	r.publish(InvalidationPattern, keyPattern)
+}
+
+// InvalidateTag removes the entries tagged with tag on this and every other replica
+func (r *ReplicatedCache) InvalidateTag(tag string) {
+	r.Cache.InvalidateTag(tag)
+	r.publish(InvalidationTag, tag)
+}
+
+// Close closes the transport and stops the janitor of the local cache
+func (r *ReplicatedCache) Close() {
+	if err := r.transport.Close(); err != nil {
+		r.logger.Error("[go-goods] failed to close cache invalidation transport", zap.Error(err))
+	}
+	r.Cache.Close()
+}
+
+func (r *ReplicatedCache) publish(kind InvalidationKind, value string) {
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+
+	message := Invalidation{Origin: r.id, Kind: kind, Value: value}
+	if err := r.transport.Publish(ctx, message); err != nil {
+		r.logger.Error("[go-goods] failed to publish cache invalidation",
+			zap.String("kind", string(kind)),
+			zap.String("value", value),
+			zap.Error(err),
+		)
+	}
+}
+
+// apply removes the entries of an invalidation received from another replica
+func (r *ReplicatedCache) apply(message Invalidation) {
+	if message.Origin == r.id {
+		return
+	}
+	r.logger.Debug("[go-goods] cache invalidation received",
+		zap.String("origin", message.Origin),
+		zap.String("kind", string(message.Kind)),
+		zap.String("value", message.Value),
+	)
+
+	switch message.Kind {
+	case InvalidationKey:
+		r.Cache.Delete(message.Value)
+	case InvalidationPrefix:
+		r.Cache.DeletePrefix(message.Value)
+	case InvalidationPattern:
+		r.Cache.DeleteAll(message.Value)
+	case InvalidationTag:
+		r.Cache.InvalidateTag(message.Value)
+	default:
+		r.logger.Warn("[go-goods] unknown cache invalidation kind", zap.String("kind", string(message.Kind)))
+	}
+}
+
+// newOriginID returns a random ID identifying a replica
+func newOriginID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,261 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	goodsHTTP "github.com/AnhCaooo/go-goods/http"
+	"go.uber.org/zap"
+)
+
+// newReplicas returns n replicated caches holding the same entries and connected through one memory bus
+func newReplicas(n int) []*ReplicatedCache {
+	bus := NewMemoryBus()
+	replicas := make([]*ReplicatedCache, n)
+	for i := range replicas {
+		replicas[i] = NewReplicatedCache(zap.NewNop(), NewCache(zap.NewNop()), bus.Transport())
+		replicas[i].SetWithTags("user:1", "alice", time.Minute, "team:a")
+		replicas[i].SetWithTags("user:2", "bob", time.Minute, "team:b")
+		replicas[i].SetExpiredAfterTimePeriod("post:1", "hello", time.Minute)
+	}
+	return replicas
+}
+
+func TestReplicatedCacheInvalidation(t *testing.T) {
+	tests := []struct {
+		name            string
+		act             func(c *ReplicatedCache)
+		expectedPresent []string
+		expectedAbsent  []string
+	}{
+		{
+			name:            "Delete",
+			act:             func(c *ReplicatedCache) { c.Delete("user:1") },
+			expectedPresent: []string{"user:2", "post:1"},
+			expectedAbsent:  []string{"user:1"},
+		},
+		{
+			name:            "DeleteMany",
+			act:             func(c *ReplicatedCache) { c.DeleteMany("user:1", "post:1") },
+			expectedPresent: []string{"user:2"},
+			expectedAbsent:  []string{"user:1", "post:1"},
+		},
+		{
+			name:            "DeletePrefix",
+			act:             func(c *ReplicatedCache) { c.DeletePrefix("user:") },
+			expectedPresent: []string{"post:1"},
+			expectedAbsent:  []string{"user:1", "user:2"},
+		},
+		{
+			name:            "DeleteAll",
+			act:             func(c *ReplicatedCache) { c.DeleteAll(":1") },
+			expectedPresent: []string{"user:2"},
+			expectedAbsent:  []string{"user:1", "post:1"},
+		},
+		{
+			name:            "InvalidateTag",
+			act:             func(c *ReplicatedCache) { c.InvalidateTag("team:b") },
+			expectedPresent: []string{"user:1", "post:1"},
+			expectedAbsent:  []string{"user:2"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			replicas := newReplicas(3)
+			test.act(replicas[0])
+
+			for i, replica := range replicas {
+				for _, key := range test.expectedPresent {
+					if _, found := replica.Get(key); !found {
+						t.Errorf("Replica %d: key %s should be present", i, key)
+					}
+				}
+				for _, key := range test.expectedAbsent {
+					if _, found := replica.Get(key); found {
+						t.Errorf("Replica %d: key %s should have been removed", i, key)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestReplicatedCacheIgnoresOwnInvalidations(t *testing.T) {
+	replica := newReplicas(1)[0]
+
+	replica.apply(Invalidation{Origin: replica.ID(), Kind: InvalidationKey, Value: "user:1"})
+	if _, found := replica.Get("user:1"); !found {
+		t.Error("An invalidation sent by the replica itself should be ignored")
+	}
+
+	replica.apply(Invalidation{Origin: "other", Kind: InvalidationKey, Value: "user:1"})
+	if _, found := replica.Get("user:1"); found {
+		t.Error("An invalidation sent by another replica should be applied")
+	}
+}
+
+func TestReplicatedCacheClose(t *testing.T) {
+	replicas := newReplicas(2)
+	replicas[1].Close()
+
+	replicas[0].Delete("user:1")
+
+	if _, found := replicas[1].Get("user:1"); !found {
+		t.Error("A closed replica should not receive invalidations")
+	}
+}
+
+// newHTTPReplicas returns replicated caches whose HTTP transports are served by test servers and post to each other
+func newHTTPReplicas(t *testing.T, secrets ...string) []*ReplicatedCache {
+	servers := make([]*httptest.Server, len(secrets))
+	mux := make([]*http.ServeMux, len(secrets))
+	for i := range servers {
+		mux[i] = http.NewServeMux()
+		servers[i] = httptest.NewServer(mux[i])
+		t.Cleanup(servers[i].Close)
+	}
+
+	replicas := make([]*ReplicatedCache, len(secrets))
+	for i := range replicas {
+		var peers []string
+		for j, server := range servers {
+			if j != i {
+				peers = append(peers, server.URL+"/invalidate")
+			}
+		}
+		transport, err := NewHTTPTransport(zap.NewNop(), HTTPTransportConfig{Peers: peers, Secret: secrets[i]})
+		if err != nil {
+			t.Fatalf("failed to create transport: %v", err)
+		}
+		mux[i].Handle("/invalidate", transport)
+		replicas[i] = NewReplicatedCache(zap.NewNop(), NewCache(zap.NewNop()), transport)
+		replicas[i].SetExpiredAfterTimePeriod("key", "value", time.Minute)
+	}
+	return replicas
+}
+
+func TestHTTPTransport(t *testing.T) {
+	replicas := newHTTPReplicas(t, "secret", "secret", "secret")
+
+	replicas[0].Delete("key")
+
+	for i, replica := range replicas {
+		if _, found := replica.Get("key"); found {
+			t.Errorf("Replica %d: key should have been removed", i)
+		}
+	}
+}
+
+func TestHTTPTransportSecretMismatch(t *testing.T) {
+	replicas := newHTTPReplicas(t, "secret", "other")
+	transport := replicas[0].transport
+
+	err := transport.Publish(context.Background(), Invalidation{Origin: replicas[0].ID(), Kind: InvalidationKey, Value: "key"})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected an unauthorized error, got %v", err)
+	}
+	if _, found := replicas[1].Get("key"); !found {
+		t.Error("An invalidation with a wrong secret should be rejected")
+	}
+}
+
+func TestNewHTTPTransportRequiresSecret(t *testing.T) {
+	if _, err := NewHTTPTransport(zap.NewNop(), HTTPTransportConfig{Peers: []string{"http://prices-2/invalidate"}}); err == nil {
+		t.Error("Expected an error for a transport without a secret")
+	}
+}
+
+func TestHTTPTransportServeHTTP(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		secret         string
+		body           string
+		closed         bool
+		expectedStatus int
+		expectedKey    goodsHTTP.TranslationKey
+		expectedValues []string
+	}{
+		{
+			name:           "Valid invalidation",
+			method:         http.MethodPost,
+			secret:         "secret",
+			body:           `{"origin":"other","kind":"key","value":"key"}`,
+			expectedStatus: http.StatusNoContent,
+			expectedValues: []string{"key"},
+		},
+		{
+			name:           "Wrong method",
+			method:         http.MethodGet,
+			secret:         "secret",
+			expectedStatus: http.StatusMethodNotAllowed,
+			expectedKey:    goodsHTTP.InvalidRequest,
+		},
+		{
+			name:           "Missing secret",
+			method:         http.MethodPost,
+			body:           `{"origin":"other","kind":"key","value":"key"}`,
+			expectedStatus: http.StatusUnauthorized,
+			expectedKey:    goodsHTTP.Unauthorized,
+		},
+		{
+			name:           "Malformed body",
+			method:         http.MethodPost,
+			secret:         "secret",
+			body:           `{"origin":`,
+			expectedStatus: http.StatusBadRequest,
+			expectedKey:    goodsHTTP.InvalidRequest,
+		},
+		{
+			name:           "Closed transport",
+			method:         http.MethodPost,
+			secret:         "secret",
+			body:           `{"origin":"other","kind":"key","value":"key"}`,
+			closed:         true,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedKey:    goodsHTTP.InternalServer,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transport, err := NewHTTPTransport(zap.NewNop(), HTTPTransportConfig{Secret: "secret"})
+			if err != nil {
+				t.Fatalf("failed to create transport: %v", err)
+			}
+			var received []string
+			transport.Subscribe(func(message Invalidation) {
+				received = append(received, message.Value)
+			})
+			if test.closed {
+				transport.Close()
+			}
+
+			req := httptest.NewRequest(test.method, "/invalidate", strings.NewReader(test.body))
+			if test.secret != "" {
+				req.Header.Set(InvalidationSecretHeader, test.secret)
+			}
+			rr := httptest.NewRecorder()
+			transport.ServeHTTP(rr, req)
+
+			if rr.Code != test.expectedStatus {
+				t.Errorf("Expected status %d, got %d", test.expectedStatus, rr.Code)
+			}
+			if test.expectedKey != "" {
+				var httpError goodsHTTP.HTTPError
+				if err := json.NewDecoder(rr.Body).Decode(&httpError); err != nil || httpError.TranslationKey != test.expectedKey {
+					t.Errorf("Expected translation key %q, got %q (%v)", test.expectedKey, httpError.TranslationKey, err)
+				}
+			}
+			if strings.Join(received, ",") != strings.Join(test.expectedValues, ",") {
+				t.Errorf("Expected invalidations %v, got %v", test.expectedValues, received)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package cache
+
+import (
+	"iter"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/clock"
+)
+
+// All returns an iterator over the key-value pairs which have not expired yet.
+// The pairs are copied when iteration starts, so the loop body may use the cache, and entries changed
+// during the iteration are not seen. Unlike Get, All does not count hits, slide expirations or trigger refreshes.
+//
+// Example usage:
+//
+//	for key, value := range c.All() {
+//		fmt.Println(key, value)
+//	}
+func (c *Typed[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, e := range c.liveEntries(c.clock.Now()) {
+			if !yield(e.key, e.entry.Value) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the keys whose values have not expired yet. See All for details.
+func (c *Typed[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for key := range c.All() {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// Len returns the number of values which have not expired yet
+func (c *Typed[K, V]) Len() int {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	now := c.clock.Now()
+	n := 0
+	for _, entry := range c.data {
+		if !now.After(entry.Expiration) {
+			n++
+		}
+	}
+	return n
+}
+
+// Peek returns the value of key if it has not expired yet. Unlike Get, it does not count a hit or miss,
+// record usage for eviction, slide the expiration or trigger a refresh, so it suits inspecting the cache.
+func (c *Typed[K, V]) Peek(key K) (V, bool) {
+	entry, found := c.peek(key)
+	return entry.Value, found
+}
+
+// TTL returns how long the value of key stays in the cache, and `false` if it is missing or expired.
+// For a value with sliding expiration, it is the time left until it expires if it is not read again.
+func (c *Typed[K, V]) TTL(key K) (time.Duration, bool) {
+	entry, found := c.peek(key)
+	if !found {
+		return 0, false
+	}
+	return entry.Expiration.Sub(c.clock.Now()), true
+}
+
+// Clock returns the clock the cache reads the current time from, so code comparing times with its entries can use the same one
+func (c *Typed[K, V]) Clock() clock.Clock {
+	return c.clock
+}
+
+// GetMany retrieves the values of keys which are in the cache and have not expired yet.
+// Each key is looked up like with Get, and missing keys are left out of the result.
+//
+// Example usage:
+//
+//	found := prices.GetMany(yesterday, today, tomorrow)
+//	if todayPrices, ok := found[today]; ok {
+//		// use todayPrices
+//	}
+func (c *Typed[K, V]) GetMany(keys ...K) map[K]V {
+	values := make(map[K]V, len(keys))
+	for _, key := range keys {
+		if value, found := c.Get(key); found {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// SetMany adds all key-value pairs of values to the cache under one lock. Every value expires after the given duration counted from now.
+func (c *Typed[K, V]) SetMany(values map[K]V, duration time.Duration) {
+	c.lock.Lock()
+	defer c.unlock()
+
+	expirationTime := c.clock.Now().Add(duration)
+	for key, value := range values {
+		c.store(key, Entry[V]{
+			Value:      value,
+			Expiration: expirationTime,
+			ttl:        duration,
+		})
+	}
+}
+
+// DeleteMany removes the values of keys from the cache under one lock. Missing keys are ignored.
+func (c *Typed[K, V]) DeleteMany(keys ...K) {
+	c.lock.Lock()
+	defer c.unlock()
+
+	for _, key := range keys {
+		c.remove(key, ReasonDeleted)
+	}
+}
@@ -0,0 +1,208 @@
+package cache
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/clock"
+	"go.uber.org/zap"
+)
+
+// newIterateCache returns a cache with two live entries and one expired entry
+func newIterateCache() (*Typed[string, int], *clock.Fake) {
+	clk := clock.NewFake(time.Now())
+	c := NewTyped[string, int](zap.NewNop(), WithClock(clk))
+	c.SetExpiredAfterTimePeriod("one", 1, time.Hour)
+	c.SetExpiredAfterTimePeriod("two", 2, 2*time.Hour)
+	c.SetExpiredAfterTimePeriod("expired", 3, time.Minute)
+	clk.Advance(30 * time.Minute)
+	return c, clk
+}
+
+func TestAll(t *testing.T) {
+	c, _ := newIterateCache()
+
+	got := maps.Collect(c.All())
+	expected := map[string]int{"one": 1, "two": 2}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	keys := slices.Sorted(c.Keys())
+	if !reflect.DeepEqual(keys, []string{"one", "two"}) {
+		t.Errorf("Expected keys [one two], got %v", keys)
+	}
+	if stats := c.Stats(); stats.Hits != 0 || stats.Misses != 0 {
+		t.Errorf("Iteration should not count hits or misses, got %+v", stats)
+	}
+}
+
+func TestAllStopsEarly(t *testing.T) {
+	c, _ := newIterateCache()
+
+	n := 0
+	for range c.All() {
+		n++
+		break
+	}
+	if n != 1 {
+		t.Errorf("Expected iteration to stop after 1 entry, got %d", n)
+	}
+}
+
+func TestAllAllowsUsingTheCache(t *testing.T) {
+	c, _ := newIterateCache()
+
+	for key := range c.Keys() {
+		c.Delete(key)
+	}
+	if c.Len() != 0 {
+		t.Errorf("Expected every key to be deleted during iteration, got %d left", c.Len())
+	}
+}
+
+func TestLen(t *testing.T) {
+	c, clk := newIterateCache()
+
+	if n := c.Len(); n != 2 {
+		t.Errorf("Expected 2 live entries, got %d", n)
+	}
+	clk.Advance(time.Hour)
+	if n := c.Len(); n != 1 {
+		t.Errorf("Expected 1 live entry, got %d", n)
+	}
+}
+
+func TestTTL(t *testing.T) {
+	tests := []struct {
+		name          string
+		key           string
+		expectedTTL   time.Duration
+		expectedFound bool
+	}{
+		{name: "Live value", key: "one", expectedTTL: 30 * time.Minute, expectedFound: true},
+		{name: "Expired value", key: "expired"},
+		{name: "Missing value", key: "missing"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, _ := newIterateCache()
+
+			ttl, found := c.TTL(test.key)
+			if found != test.expectedFound || ttl != test.expectedTTL {
+				t.Errorf("Expected (%v, %v), got (%v, %v)", test.expectedTTL, test.expectedFound, ttl, found)
+			}
+		})
+	}
+}
+
+func TestTTLSlidingExpiration(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	c := NewTyped[string, string](zap.NewNop(), WithClock(clk))
+	c.SetWithSlidingExpiration("session", "alice", time.Minute, time.Hour)
+	clk.Advance(20 * time.Second)
+
+	if ttl, _ := c.TTL("session"); ttl != 40*time.Second {
+		t.Errorf("Expected TTL 40s before Get, got %v", ttl)
+	}
+	c.Get("session")
+	if ttl, _ := c.TTL("session"); ttl != time.Minute {
+		t.Errorf("Expected TTL 1m after Get, got %v", ttl)
+	}
+}
+
+func TestGetMany(t *testing.T) {
+	c, _ := newIterateCache()
+
+	got := c.GetMany("one", "expired", "missing", "two")
+	expected := map[string]int{"one": 1, "two": 2}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if stats := c.Stats(); stats.Hits != 2 || stats.Misses != 2 {
+		t.Errorf("Expected 2 hits and 2 misses, got %+v", stats)
+	}
+}
+
+func TestSetMany(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	c := NewTyped[string, int](zap.NewNop(), WithClock(clk), WithMaxEntries(2))
+	c.SetMany(map[string]int{"one": 1, "two": 2, "three": 3}, time.Minute)
+
+	if n := c.Len(); n != 2 {
+		t.Errorf("Expected the cache to stay within 2 entries, got %d", n)
+	}
+	for key := range c.Keys() {
+		if ttl, _ := c.TTL(key); ttl != time.Minute {
+			t.Errorf("Expected %q to expire in 1m, got %v", key, ttl)
+		}
+	}
+}
+
+func TestDeleteMany(t *testing.T) {
+	c := NewTyped[string, int](zap.NewNop())
+	var lock sync.Mutex
+	var deleted []string
+	c.OnEvict(func(key string, value int, reason EvictionReason) {
+		lock.Lock()
+		defer lock.Unlock()
+		deleted = append(deleted, key)
+	})
+	c.SetMany(map[string]int{"one": 1, "two": 2, "three": 3}, time.Minute)
+
+	c.DeleteMany("one", "three", "missing")
+
+	if keys := slices.Collect(c.Keys()); !reflect.DeepEqual(keys, []string{"two"}) {
+		t.Errorf("Expected keys [two], got %v", keys)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	slices.Sort(deleted)
+	if !reflect.DeepEqual(deleted, []string{"one", "three"}) {
+		t.Errorf("Expected callbacks for [one three], got %v", deleted)
+	}
+}
+
+func TestShardedBulk(t *testing.T) {
+	c := NewSharded[string, int](zap.NewNop(), 4)
+	values := map[string]int{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5}
+	c.SetMany(values, time.Minute)
+
+	if got := maps.Collect(c.All()); !reflect.DeepEqual(got, values) {
+		t.Errorf("Expected %v, got %v", values, got)
+	}
+	if n := c.Len(); n != len(values) {
+		t.Errorf("Expected %d entries, got %d", len(values), n)
+	}
+	if ttl, found := c.TTL("a"); !found || ttl <= 0 || ttl > time.Minute {
+		t.Errorf("Expected a TTL of at most 1m, got (%v, %v)", ttl, found)
+	}
+
+	c.DeleteMany("a", "b")
+	expected := map[string]int{"c": 3, "d": 4, "e": 5}
+	if got := c.GetMany("a", "b", "c", "d", "e"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if keys := slices.Sorted(c.Keys()); !reflect.DeepEqual(keys, []string{"c", "d", "e"}) {
+		t.Errorf("Expected keys [c d e], got %v", keys)
+	}
+}
+
+func TestPeek(t *testing.T) {
+	c, _ := newIterateCache()
+
+	if value, found := c.Peek("one"); !found || value != 1 {
+		t.Errorf("Expected (1, true), got (%v, %v)", value, found)
+	}
+	if _, found := c.Peek("expired"); found {
+		t.Error("Expired value should not be found")
+	}
+	if stats := c.Stats(); stats.Hits != 0 || stats.Misses != 0 {
+		t.Errorf("Peek should not count hits or misses, got %+v", stats)
+	}
+}
@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// janitorBatchSize is the maximum number of entries purged while holding the lock once.
+// The lock is released between batches so readers and writers are not blocked during a long purge.
+const janitorBatchSize = 256
+
+// WithJanitor starts a background goroutine that removes expired entries every interval,
+// so keys that are written once and never read again do not stay in memory forever.
+// The janitor stops when ctx is cancelled or when Close is called on the cache.
+// A value <= 0 for interval disables the janitor, which is the default.
+//
+// Example usage:
+//
+//	c := cache.NewCache(logger, cache.WithJanitor(ctx, time.Minute))
+//	defer c.Close()
+func WithJanitor(ctx context.Context, interval time.Duration) Option {
+	return func(o *options) {
+		o.janitorCtx = ctx
+		o.janitorInterval = interval
+	}
+}
+
+// janitor runs a purge function periodically in a background goroutine
+type janitor struct {
+	stop context.CancelFunc
+	done chan struct{}
+}
+
+// startJanitor runs purge every interval until ctx is cancelled or the janitor is closed
+func startJanitor(ctx context.Context, interval time.Duration, logger *zap.Logger, purge func() int) *janitor {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, stop := context.WithCancel(ctx)
+	j := &janitor{
+		stop: stop,
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(j.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if purged := purge(); purged > 0 {
+					logger.Debug("[go-goods] cache janitor purged expired entries", zap.Int("count", purged))
+				}
+			}
+		}
+	}()
+	return j
+}
+
+// close stops the janitor and waits for it to exit. It is a no-op on a nil janitor.
+func (j *janitor) close() {
+	if j == nil {
+		return
+	}
+	j.stop()
+	<-j.done
+}
+
+// Close stops the background janitor, if any, and waits for it to exit. It is safe to call Close more than once.
+func (c *Typed[K, V]) Close() {
+	c.janitor.close()
+}
+
+// purgeExpired removes every expired entry and returns how many were removed.
+// It works in batches and never holds the lock for a whole pass over the cache.
+func (c *Typed[K, V]) purgeExpired() int {
+	purged := 0
+	for {
+		n := c.purgeExpiredBatch(c.clock.Now())
+		purged += n
+		if n < janitorBatchSize {
+			return purged
+		}
+	}
+}
+
+// purgeExpiredBatch removes up to janitorBatchSize entries which expired before now
+func (c *Typed[K, V]) purgeExpiredBatch(now time.Time) int {
+	c.lock.Lock()
+	defer c.unlock()
+
+	purged := 0
+	for purged < janitorBatchSize {
+		item, ok := c.expirations.peek()
+		if !ok || !now.After(item.expiration) {
+			break
+		}
+
+		value, exists := c.data[item.key]
+		if exists && !now.After(value.Expiration) {
+			// the entry was replaced directly through Cache.Data, so track its real expiration
+			c.expirations.set(item.key, value.Expiration)
+			continue
+		}
+		c.remove(item.key, ReasonExpired)
+		purged++
+	}
+	return purged
+}
@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/clock"
+	"go.uber.org/zap"
+)
+
+func TestPurgeExpired(t *testing.T) {
+	tests := []struct {
+		name           string
+		expiredCount   int
+		liveCount      int
+		expectedPurged int
+	}{
+		{
+			name:           "Purge from empty cache",
+			expectedPurged: 0,
+		},
+		{
+			name:           "Purge only expired entries",
+			expiredCount:   10,
+			liveCount:      5,
+			expectedPurged: 10,
+		},
+		{
+			name:           "Purge more entries than one batch",
+			expiredCount:   3*janitorBatchSize + 7,
+			liveCount:      janitorBatchSize,
+			expectedPurged: 3*janitorBatchSize + 7,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Now())
+			c := NewCache(zap.NewNop(), WithClock(clk))
+			for i := 0; i < test.expiredCount; i++ {
+				c.SetExpiredAfterTimePeriod(fmt.Sprintf("expired:%d", i), i, time.Minute)
+			}
+			for i := 0; i < test.liveCount; i++ {
+				c.SetExpiredAfterTimePeriod(fmt.Sprintf("live:%d", i), i, time.Hour)
+			}
+			clk.Advance(2 * time.Minute)
+
+			purged := c.purgeExpired()
+			if purged != test.expectedPurged {
+				t.Errorf("Expected %d purged entries, got %d", test.expectedPurged, purged)
+			}
+			if len(c.data) != test.liveCount {
+				t.Errorf("Expected %d remaining entries, got %d", test.liveCount, len(c.data))
+			}
+			if c.expirations.Len() != test.liveCount {
+				t.Errorf("Expected %d tracked expirations, got %d", test.liveCount, c.expirations.Len())
+			}
+		})
+	}
+}
+
+func TestPurgeExpiredKeepsEntriesReplacedThroughData(t *testing.T) {
+	c := NewCache(zap.NewNop())
+	c.SetExpiredAfterTimePeriod("key", "old", -time.Minute)
+	c.Data["key"] = CacheValue{Value: "new", Expiration: time.Now().Add(time.Hour)}
+
+	if purged := c.purgeExpired(); purged != 0 {
+		t.Errorf("Expected no purged entries, got %d", purged)
+	}
+	if value, exists := c.Get("key"); !exists || value != "new" {
+		t.Errorf("Expected (new, true), got (%v, %v)", value, exists)
+	}
+}
+
+func TestJanitorRunsInBackground(t *testing.T) {
+	// entries expire on the fake clock, only the janitor's ticker is real
+	clk := clock.NewFake(time.Now())
+	c := NewCache(zap.NewNop(), WithClock(clk), WithJanitor(context.Background(), time.Millisecond))
+	defer c.Close()
+
+	expired := make(chan string, 1)
+	c.OnExpire(func(key string, value interface{}, reason EvictionReason) {
+		expired <- key
+	})
+	c.SetExpiredAfterTimePeriod("short", "value", time.Minute)
+	c.SetExpiredAfterTimePeriod("long", "value", time.Hour)
+	clk.Advance(2 * time.Minute)
+
+	select {
+	case key := <-expired:
+		if key != "short" {
+			t.Errorf("Expected the janitor to purge short, got %s", key)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Janitor did not purge the expired entry")
+	}
+	if _, exists := c.Get("long"); !exists {
+		t.Error("Janitor should not purge live entries")
+	}
+}
+
+func TestJanitorStops(t *testing.T) {
+	t.Run("Close", func(t *testing.T) {
+		c := NewCache(zap.NewNop(), WithJanitor(context.Background(), time.Millisecond))
+		c.Close()
+		c.Close() // closing twice is a no-op
+
+		select {
+		case <-c.janitor.done:
+		default:
+			t.Error("Janitor should have stopped after Close")
+		}
+	})
+
+	t.Run("Context cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		c := NewCache(zap.NewNop(), WithJanitor(ctx, time.Millisecond))
+		cancel()
+
+		select {
+		case <-c.janitor.done:
+		case <-time.After(time.Second):
+			t.Error("Janitor should have stopped after context cancellation")
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		c := NewCache(zap.NewNop())
+		c.Close() // no janitor, so Close is a no-op
+		if c.janitor != nil {
+			t.Error("Janitor should not run unless configured")
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Loader fetches the value of a key which is missing from the cache, e.g. from an upstream API or a database
+type Loader[V any] func(ctx context.Context) (V, error)
+
+// loadCall is a loader call in flight. Concurrent misses on the same key wait for the same call.
+type loadCall[V any] struct {
+	done    chan struct{}
+	value   V
+	err     error
+	waiters int                // number of callers still waiting for the result
+	cancel  context.CancelFunc // cancels the loader once every waiter gave up
+}
+
+// GetOrLoad returns the cached value of key. On a miss it calls loader and caches its result for ttl.
+//
+// Concurrent misses on the same key are collapsed into a single loader call and all callers share its result or error.
+// Errors are not cached, so the next miss calls the loader again.
+// Every caller stops waiting as soon as its own ctx is done and returns ctx.Err().
+// The loader keeps running while at least one caller is waiting for it, and its context is cancelled once all of them gave up.
+//
+// Example usage:
+//
+//	prices, err := c.GetOrLoad(ctx, today, time.Hour, func(ctx context.Context) ([]Price, error) {
+//		return client.FetchPrices(ctx, today)
+//	})
+//	if err != nil {
+//		return err
+//	}
+func (c *Typed[K, V]) GetOrLoad(ctx context.Context, key K, ttl time.Duration, loader Loader[V]) (V, error) {
+	if value, found := c.Get(key); found {
+		return value, nil
+	}
+
+	c.loadLock.Lock()
+	// the value might have been loaded by another caller between Get and taking the lock
+	if entry, found := c.peek(key); found {
+		c.loadLock.Unlock()
+		return entry.Value, nil
+	}
+	call, inFlight := c.calls[key]
+	if !inFlight {
+		call = c.startLoad(ctx, c.calls, key, loader, func(value V) {
+			c.SetExpiredAfterTimePeriod(key, value, ttl)
+		})
+	}
+	call.waiters++
+	c.loadLock.Unlock()
+
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		c.abandonLoad(key, call)
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// startLoad runs the loader in the background, tracks it in calls until it returns and passes a successfully loaded value to store.
+// The caller must hold loadLock.
+func (c *Typed[K, V]) startLoad(ctx context.Context, calls map[K]*loadCall[V], key K, loader Loader[V], store func(value V)) *loadCall[V] {
+	// the loader must not stop when only the first caller gives up, but it keeps the values of its context
+	loadCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	call := &loadCall[V]{
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
+	calls[key] = call
+
+	go func() {
+		defer cancel()
+		call.value, call.err = runLoader(loadCtx, loader)
+		if call.err == nil {
+			store(call.value)
+		} else {
+			c.logger.Debug("[go-goods] cache loader failed", zap.Any("key", key), zap.Error(call.err))
+		}
+
+		c.loadLock.Lock()
+		if calls[key] == call {
+			delete(calls, key)
+		}
+		c.loadLock.Unlock()
+		close(call.done)
+	}()
+	return call
+}
+
+// abandonLoad is called when a waiter gives up. The loader is cancelled once nobody waits for it anymore.
+func (c *Typed[K, V]) abandonLoad(key K, call *loadCall[V]) {
+	c.loadLock.Lock()
+	defer c.loadLock.Unlock()
+
+	call.waiters--
+	if call.waiters > 0 {
+		return
+	}
+	call.cancel()
+	// new callers must start a fresh load instead of joining the cancelled one
+	if c.calls[key] == call {
+		delete(c.calls, key)
+	}
+}
+
+// runLoader calls the loader and turns a panic into an error, so waiters are always released
+func runLoader[V any](ctx context.Context, loader Loader[V]) (value V, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("cache loader panicked: %v", r)
+		}
+	}()
+	return loader(ctx)
+}
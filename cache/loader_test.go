@@ -0,0 +1,251 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/clock"
+	"go.uber.org/zap"
+)
+
+func TestGetOrLoadHit(t *testing.T) {
+	c := NewTyped[string, int](zap.NewNop())
+	c.SetExpiredAfterTimePeriod("key", 1, time.Minute)
+
+	value, err := c.GetOrLoad(context.Background(), "key", time.Minute, func(ctx context.Context) (int, error) {
+		t.Error("Loader should not be called on a hit")
+		return 0, nil
+	})
+	if err != nil || value != 1 {
+		t.Errorf("Expected (1, nil), got (%d, %v)", value, err)
+	}
+}
+
+func TestGetOrLoadCollapsesConcurrentMisses(t *testing.T) {
+	c := NewTyped[string, int](zap.NewNop())
+	var loads atomic.Int32
+	release := make(chan struct{})
+
+	loader := func(ctx context.Context) (int, error) {
+		loads.Add(1)
+		<-release
+		return 42, nil
+	}
+
+	const callers = 50
+	var wg sync.WaitGroup
+	results := make(chan int, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := c.GetOrLoad(context.Background(), "prices", time.Minute, loader)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			results <- value
+		}()
+	}
+
+	waitForWaiters(t, c, "prices", callers)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("Expected 1 loader call, got %d", n)
+	}
+	for value := range results {
+		if value != 42 {
+			t.Errorf("Expected 42, got %d", value)
+		}
+	}
+	if value, found := c.Get("prices"); !found || value != 42 {
+		t.Errorf("Loaded value should be cached, got (%d, %v)", value, found)
+	}
+}
+
+func TestGetOrLoadSharesErrorWithoutCaching(t *testing.T) {
+	c := NewTyped[string, int](zap.NewNop())
+	errUpstream := errors.New("upstream unavailable")
+	var loads atomic.Int32
+
+	loader := func(ctx context.Context) (int, error) {
+		loads.Add(1)
+		return 0, errUpstream
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetOrLoad(context.Background(), "key", time.Minute, loader); !errors.Is(err, errUpstream) {
+			t.Errorf("Expected %v, got %v", errUpstream, err)
+		}
+	}
+	if n := loads.Load(); n != 2 {
+		t.Errorf("Errors should not be cached, expected 2 loader calls, got %d", n)
+	}
+	if _, found := c.Get("key"); found {
+		t.Error("Failed load should not be cached")
+	}
+}
+
+func TestGetOrLoadWaiterCancellation(t *testing.T) {
+	c := NewTyped[string, int](zap.NewNop())
+	release := make(chan struct{})
+	loaderCancelled := make(chan struct{})
+
+	loader := func(ctx context.Context) (int, error) {
+		select {
+		case <-release:
+			return 7, nil
+		case <-ctx.Done():
+			close(loaderCancelled)
+			return 0, ctx.Err()
+		}
+	}
+
+	impatientCtx, cancelImpatient := context.WithCancel(context.Background())
+	impatient := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrLoad(impatientCtx, "key", time.Minute, loader)
+		impatient <- err
+	}()
+	waitForWaiters(t, c, "key", 1)
+
+	patient := make(chan int, 1)
+	go func() {
+		value, _ := c.GetOrLoad(context.Background(), "key", time.Minute, loader)
+		patient <- value
+	}()
+	waitForWaiters(t, c, "key", 2)
+
+	cancelImpatient()
+	if err := <-impatient; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+
+	close(release)
+	if value := <-patient; value != 7 {
+		t.Errorf("Remaining waiter should get the loaded value, got %d", value)
+	}
+	select {
+	case <-loaderCancelled:
+		t.Error("Loader should not be cancelled while a caller is still waiting")
+	default:
+	}
+}
+
+func TestGetOrLoadCancelsLoaderWhenAllWaitersLeave(t *testing.T) {
+	c := NewTyped[string, int](zap.NewNop())
+	loaderCancelled := make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.GetOrLoad(ctx, "key", time.Minute, func(ctx context.Context) (int, error) {
+			<-ctx.Done()
+			close(loaderCancelled)
+			return 0, ctx.Err()
+		})
+		done <- err
+	}()
+	waitForWaiters(t, c, "key", 1)
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+	select {
+	case <-loaderCancelled:
+	case <-time.After(time.Second):
+		t.Fatal("Loader should be cancelled once every caller gave up")
+	}
+}
+
+func TestGetOrLoadRecoversPanic(t *testing.T) {
+	c := NewTyped[string, int](zap.NewNop())
+	_, err := c.GetOrLoad(context.Background(), "key", time.Minute, func(ctx context.Context) (int, error) {
+		panic("boom")
+	})
+	if err == nil {
+		t.Error("Expected an error from a panicking loader")
+	}
+}
+
+func TestGetOrLoadDoesNotJoinRefresh(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	c := NewTyped[string, int](zap.NewNop(), WithClock(clk))
+	release := make(chan struct{})
+	refreshCancelled := make(chan struct{})
+	c.RegisterLoader(func(ctx context.Context, key string) (int, error) {
+		select {
+		case <-release:
+			return 2, nil
+		case <-ctx.Done():
+			close(refreshCancelled)
+			return 0, ctx.Err()
+		}
+	})
+
+	c.SetWithSoftTTL("key", 1, time.Minute, 2*time.Minute)
+	clk.Advance(90 * time.Second)
+	c.Get("key") // starts a background refresh which blocks until release
+	clk.Advance(time.Minute)
+
+	// a caller which gives up on a miss must not cancel the refresh
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.GetOrLoad(ctx, "key", time.Hour, func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected %v, got %v", context.Canceled, err)
+	}
+
+	value, err := c.GetOrLoad(context.Background(), "key", time.Hour, func(ctx context.Context) (int, error) {
+		return 3, nil
+	})
+	if err != nil || value != 3 {
+		t.Fatalf("Miss should run its own loader, got (%d, %v)", value, err)
+	}
+	c.lock.RLock()
+	entry, found := c.data["key"]
+	c.lock.RUnlock()
+	if !found || entry.ttl != time.Hour {
+		t.Errorf("Loaded value should be cached for the ttl of GetOrLoad, got (%+v, %v)", entry, found)
+	}
+
+	select {
+	case <-refreshCancelled:
+		t.Error("Background refresh should not be cancelled by a GetOrLoad caller")
+	default:
+	}
+	close(release)
+	waitFor(t, func() bool {
+		c.loadLock.Lock()
+		defer c.loadLock.Unlock()
+		return len(c.refreshes) == 0
+	})
+}
+
+// waitForWaiters blocks until the given number of callers wait for the load of key
+func waitForWaiters[K comparable, V any](t *testing.T, c *Typed[K, V], key K, waiters int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		c.loadLock.Lock()
+		call, ok := c.calls[key]
+		ready := ok && call.waiters == waiters
+		c.loadLock.Unlock()
+		if ready {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %d waiters", waiters)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/clock"
+)
+
+// Option configures optional behaviour of a cache at construction time.
+//
+// Example usage:
+//
+//	c := cache.NewCache(logger,
+//		cache.WithMaxEntries(10_000),
+//		cache.WithEvictionPolicy(cache.LFU),
+//	)
+type Option func(*options)
+
+type options struct {
+	maxEntries      int
+	maxBytes        int64
+	sizer           Sizer
+	policy          EvictionPolicy
+	janitorCtx      context.Context
+	janitorInterval time.Duration
+	refreshAhead    time.Duration
+	snapshotKey     []byte
+	clock           clock.Clock
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		policy: LRU,
+		sizer:  DefaultSizer,
+		clock:  clock.Real{},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithMaxEntries bounds the number of entries kept in the cache.
+// When a new key would exceed the limit, an entry is evicted according to the configured EvictionPolicy.
+// A value <= 0 means the cache is unbounded, which is the default.
+func WithMaxEntries(maxEntries int) Option {
+	return func(o *options) {
+		o.maxEntries = maxEntries
+	}
+}
+
+// WithEvictionPolicy selects which entry is evicted when the cache is full. Default is LRU.
+// It has no effect unless the cache is bounded by WithMaxEntries or WithMaxBytes.
+func WithEvictionPolicy(policy EvictionPolicy) Option {
+	return func(o *options) {
+		o.policy = policy
+	}
+}
+
+// WithClock sets the clock the cache reads the current time from. Default is the system clock.
+// Tests can pass a clock.Fake to expire entries without sleeping.
+//
+// Example usage:
+//
+//	clk := clock.NewFake(time.Now())
+//	c := cache.NewCache(logger, cache.WithClock(clk))
+//	c.SetExpiredAfterTimePeriod("key", "value", time.Minute)
+//	clk.Advance(2 * time.Minute) // "key" has expired
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = c
+	}
+}
@@ -0,0 +1,325 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultRedisPoolSize    = 10
+	defaultRedisDialTimeout = 5 * time.Second
+	defaultRedisTimeout     = 3 * time.Second
+	redisScanCount          = 100
+)
+
+var errRedisStoreClosed = errors.New("redis store is closed")
+
+// RedisConfig configures the connection of a RedisStore
+type RedisConfig struct {
+	Addr        string        // Addr is the host:port of the Redis server
+	Password    string        // Password is sent with AUTH when it is not empty
+	DB          int           // DB is the database selected with SELECT
+	KeyPrefix   string        // KeyPrefix is prepended to every key, so several services can share one server
+	PoolSize    int           // PoolSize is the maximum number of idle connections kept open. Default is 10
+	DialTimeout time.Duration // DialTimeout bounds opening a connection. Default is 5 seconds
+	Timeout     time.Duration // Timeout bounds every command. Default is 3 seconds
+}
+
+// RedisStore is a Store keeping values in a Redis server, so all replicas of a service share them
+// and an invalidation is seen by every replica. It speaks the Redis protocol over a small connection pool
+// and works with any server compatible with it.
+//
+// Values are encoded with a TypeCodec, so types other than the common built-in ones must be registered with RegisterType.
+// A Store cannot return errors, so a failing command is logged and a failing Get is treated as a miss.
+type RedisStore struct {
+	logger *zap.Logger
+	config RedisConfig
+	types  *TypeCodec
+	lock   sync.Mutex
+	idle   []*respConn // guarded by lock
+	closed bool        // guarded by lock
+}
+
+// NewRedisStore returns a RedisStore connected to the server of config.
+// It returns an error if the server cannot be reached.
+//
+// Example usage:
+//
+//	store, err := cache.NewRedisStore(logger, cache.RedisConfig{Addr: "localhost:6379", KeyPrefix: "prices:"})
+//	if err != nil {
+//		return err
+//	}
+//	defer store.Close()
+//	store.RegisterType("prices", []Price{})
+func NewRedisStore(logger *zap.Logger, config RedisConfig) (*RedisStore, error) {
+	if config.Addr == "" {
+		return nil, errors.New("failed to create redis store: address is missing")
+	}
+	if config.PoolSize <= 0 {
+		config.PoolSize = defaultRedisPoolSize
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = defaultRedisDialTimeout
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultRedisTimeout
+	}
+
+	s := &RedisStore{
+		logger: logger,
+		config: config,
+		types:  NewTypeCodec(),
+	}
+	if _, err := s.do("PING"); err != nil {
+		return nil, fmt.Errorf("failed to connect to redis at %s: %s", config.Addr, err.Error())
+	}
+	return s, nil
+}
+
+// RegisterType registers the type of sample under name, so cached values of that type can be read back.
+func (s *RedisStore) RegisterType(name string, sample interface{}) {
+	s.types.Register(name, sample)
+}
+
+// SetExpiredAfterTimePeriod caches the value for the given duration
+func (s *RedisStore) SetExpiredAfterTimePeriod(key string, value interface{}, duration time.Duration) {
+	if duration <= 0 {
+		// the value would already be expired, which Redis rejects
+		s.Delete(key)
+		return
+	}
+	s.set(key, value, "PX", strconv.FormatInt(max(duration.Milliseconds(), 1), 10))
+}
+
+// SetExpiredAtTime caches the value until the given time
+func (s *RedisStore) SetExpiredAtTime(key string, value interface{}, expiredTime time.Time) {
+	if !expiredTime.After(time.Now()) {
+		s.Delete(key)
+		return
+	}
+	s.set(key, value, "PXAT", strconv.FormatInt(expiredTime.UnixMilli(), 10))
+}
+
+func (s *RedisStore) set(key string, value interface{}, expiration string, at string) {
+	data, err := s.types.Encode(value)
+	if err != nil {
+		s.logger.Error("[go-goods] failed to encode value for redis cache", zap.String("key", key), zap.Error(err))
+		return
+	}
+	if _, err := s.do("SET", s.config.KeyPrefix+key, string(data), expiration, at); err != nil {
+		s.logger.Error("[go-goods] failed to set value in redis cache", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// Get returns the cached value and `true`, or `nil` and `false` if it is missing, expired or cannot be read.
+func (s *RedisStore) Get(key string) (interface{}, bool) {
+	reply, err := s.do("GET", s.config.KeyPrefix+key)
+	if err != nil {
+		s.logger.Error("[go-goods] failed to get value from redis cache", zap.String("key", key), zap.Error(err))
+		return nil, false
+	}
+	data, ok := reply.([]byte)
+	if !ok || data == nil {
+		s.logger.Debug("[go-goods] cache key was not found from cache", zap.String("key", key))
+		return nil, false
+	}
+
+	value, err := s.types.Decode(data)
+	if err != nil {
+		s.logger.Error("[go-goods] failed to decode value from redis cache", zap.String("key", key), zap.Error(err))
+		return nil, false
+	}
+	return value, true
+}
+
+// TTL returns how long the value of key stays in Redis, using PTTL.
+// It returns `false` if the key is missing, has no expiration or the command fails.
+func (s *RedisStore) TTL(key string) (time.Duration, bool) {
+	reply, err := s.do("PTTL", s.config.KeyPrefix+key)
+	if err != nil {
+		s.logger.Error("[go-goods] failed to get ttl from redis cache", zap.String("key", key), zap.Error(err))
+		return 0, false
+	}
+	ms, ok := reply.(int64)
+	if !ok || ms < 0 {
+		// -2 means the key is missing and -1 that it has no expiration
+		return 0, false
+	}
+	return time.Duration(ms) * time.Millisecond, true
+}
+
+// Take atomically returns and removes the value of key with GETDEL, which needs Redis 6.2 or later,
+// so only one of concurrent callers, on any replica, gets it. A failing command is logged and treated as a miss.
+func (s *RedisStore) Take(key string) (interface{}, bool) {
+	reply, err := s.do("GETDEL", s.config.KeyPrefix+key)
+	if err != nil {
+		s.logger.Error("[go-goods] failed to take value from redis cache", zap.String("key", key), zap.Error(err))
+		return nil, false
+	}
+	data, ok := reply.([]byte)
+	if !ok || data == nil {
+		return nil, false
+	}
+
+	value, err := s.types.Decode(data)
+	if err != nil {
+		s.logger.Error("[go-goods] failed to decode value from redis cache", zap.String("key", key), zap.Error(err))
+		return nil, false
+	}
+	return value, true
+}
+
+// Delete removes the value of key. It is a no-op if the key is not cached
+func (s *RedisStore) Delete(key string) {
+	if _, err := s.do("DEL", s.config.KeyPrefix+key); err != nil {
+		s.logger.Error("[go-goods] failed to delete value from redis cache", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// DeletePrefix removes every value whose key starts with prefix.
+// Keys are found with SCAN, so it does not block the server but may miss keys written while it runs.
+func (s *RedisStore) DeletePrefix(prefix string) {
+	pattern := escapeRedisPattern(s.config.KeyPrefix+prefix) + "*"
+	cursor := "0"
+	for {
+		reply, err := s.do("SCAN", cursor, "MATCH", pattern, "COUNT", strconv.Itoa(redisScanCount))
+		if err != nil {
+			s.logger.Error("[go-goods] failed to scan redis cache", zap.String("prefix", prefix), zap.Error(err))
+			return
+		}
+		next, keys, err := parseScanReply(reply)
+		if err != nil {
+			s.logger.Error("[go-goods] failed to scan redis cache", zap.String("prefix", prefix), zap.Error(err))
+			return
+		}
+		if len(keys) > 0 {
+			if _, err := s.do(append([]string{"DEL"}, keys...)...); err != nil {
+				s.logger.Error("[go-goods] failed to delete values from redis cache", zap.String("prefix", prefix), zap.Error(err))
+				return
+			}
+		}
+		if next == "0" {
+			return
+		}
+		cursor = next
+	}
+}
+
+// Close closes all connections. The store must not be used afterwards. It is safe to call Close more than once.
+func (s *RedisStore) Close() {
+	s.lock.Lock()
+	idle := s.idle
+	s.idle = nil
+	s.closed = true
+	s.lock.Unlock()
+
+	for _, conn := range idle {
+		conn.close()
+	}
+}
+
+// do runs a command on a pooled connection
+func (s *RedisStore) do(args ...string) (interface{}, error) {
+	conn, err := s.acquire()
+	if err != nil {
+		return nil, err
+	}
+	reply, err := conn.do(args...)
+	s.release(conn, err)
+	return reply, err
+}
+
+// acquire returns an idle connection or opens a new one
+func (s *RedisStore) acquire() (*respConn, error) {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		return nil, errRedisStoreClosed
+	}
+	if n := len(s.idle); n > 0 {
+		conn := s.idle[n-1]
+		s.idle = s.idle[:n-1]
+		s.lock.Unlock()
+		return conn, nil
+	}
+	s.lock.Unlock()
+
+	conn, err := dialRESP(s.config.Addr, s.config.DialTimeout, s.config.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	if s.config.Password != "" {
+		if _, err := conn.do("AUTH", s.config.Password); err != nil {
+			conn.close()
+			return nil, fmt.Errorf("failed to authenticate: %s", err.Error())
+		}
+	}
+	if s.config.DB != 0 {
+		if _, err := conn.do("SELECT", strconv.Itoa(s.config.DB)); err != nil {
+			conn.close()
+			return nil, fmt.Errorf("failed to select database %d: %s", s.config.DB, err.Error())
+		}
+	}
+	return conn, nil
+}
+
+// release returns the connection to the pool, unless the command broke it or the pool is full
+func (s *RedisStore) release(conn *respConn, err error) {
+	var replyErr respError
+	if err != nil && !errors.As(err, &replyErr) {
+		conn.close()
+		return
+	}
+
+	s.lock.Lock()
+	if s.closed || len(s.idle) >= s.config.PoolSize {
+		s.lock.Unlock()
+		conn.close()
+		return
+	}
+	s.idle = append(s.idle, conn)
+	s.lock.Unlock()
+}
+
+// parseScanReply splits the reply of SCAN into the next cursor and the keys found
+func parseScanReply(reply interface{}) (string, []string, error) {
+	items, ok := reply.([]interface{})
+	if !ok || len(items) != 2 {
+		return "", nil, fmt.Errorf("unexpected SCAN reply %v", reply)
+	}
+	cursor, ok := items[0].([]byte)
+	if !ok {
+		return "", nil, fmt.Errorf("unexpected SCAN cursor %v", items[0])
+	}
+	found, ok := items[1].([]interface{})
+	if !ok {
+		return "", nil, fmt.Errorf("unexpected SCAN keys %v", items[1])
+	}
+
+	keys := make([]string, 0, len(found))
+	for _, key := range found {
+		if key, ok := key.([]byte); ok {
+			keys = append(keys, string(key))
+		}
+	}
+	return string(cursor), keys, nil
+}
+
+// escapeRedisPattern escapes the glob characters of a literal key, so it can be used in a MATCH pattern
+func escapeRedisPattern(key string) string {
+	var b strings.Builder
+	for _, r := range key {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
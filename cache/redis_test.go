@@ -0,0 +1,529 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fakeRedis is an in-process stand-in for a Redis server which understands the commands used by RedisStore
+type fakeRedis struct {
+	listener net.Listener
+	password string
+	lock     sync.Mutex
+	data     map[string]fakeRedisValue
+}
+
+type fakeRedisValue struct {
+	value      string
+	expiration time.Time
+}
+
+func startFakeRedis(t *testing.T, password string) *fakeRedis {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := &fakeRedis{listener: listener, password: password, data: make(map[string]fakeRedisValue)}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (f *fakeRedis) addr() string {
+	return f.listener.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authenticated := f.password == ""
+	for {
+		args, err := readFakeRedisCommand(reader)
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(args[0])
+		if !authenticated && command != "AUTH" {
+			io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		if command == "AUTH" {
+			if args[1] != f.password {
+				io.WriteString(conn, "-WRONGPASS invalid password\r\n")
+				continue
+			}
+			authenticated = true
+		}
+		io.WriteString(conn, f.handle(command, args[1:]))
+	}
+}
+
+func (f *fakeRedis) handle(command string, args []string) string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	switch command {
+	case "AUTH", "SELECT":
+		return "+OK\r\n"
+	case "PING":
+		return "+PONG\r\n"
+	case "SET":
+		value := fakeRedisValue{value: args[1]}
+		ms, _ := strconv.ParseInt(args[3], 10, 64)
+		switch strings.ToUpper(args[2]) {
+		case "PX":
+			value.expiration = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		case "PXAT":
+			value.expiration = time.UnixMilli(ms)
+		}
+		f.data[args[0]] = value
+		return "+OK\r\n"
+	case "GET":
+		value, ok := f.live(args[0])
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value.value), value.value)
+	case "GETDEL":
+		value, ok := f.live(args[0])
+		if !ok {
+			return "$-1\r\n"
+		}
+		delete(f.data, args[0])
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(value.value), value.value)
+	case "PTTL":
+		value, ok := f.live(args[0])
+		if !ok {
+			return ":-2\r\n"
+		}
+		return fmt.Sprintf(":%d\r\n", time.Until(value.expiration).Milliseconds())
+	case "DEL":
+		deleted := 0
+		for _, key := range args {
+			if _, ok := f.data[key]; ok {
+				delete(f.data, key)
+				deleted++
+			}
+		}
+		return fmt.Sprintf(":%d\r\n", deleted)
+	case "SCAN":
+		return f.scan(args)
+	default:
+		return "-ERR unknown command '" + command + "'\r\n"
+	}
+}
+
+// scan walks the sorted keys. The cursor is the hex encoded key to resume from,
+// so callers must follow it like with a real server and keys deleted in between do not shift the walk.
+func (f *fakeRedis) scan(args []string) string {
+	var from string
+	if args[0] != "0" {
+		key, _ := hex.DecodeString(args[0])
+		from = string(key)
+	}
+	pattern, count := "*", 10
+	for i := 1; i+1 < len(args); i += 2 {
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			pattern = args[i+1]
+		case "COUNT":
+			count, _ = strconv.Atoi(args[i+1])
+		}
+	}
+
+	keys := make([]string, 0, len(f.data))
+	for key := range f.data {
+		if key >= from {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var matched []string
+	for i := 0; i < len(keys) && i < count; i++ {
+		if matchRedisPattern(pattern, keys[i]) {
+			matched = append(matched, keys[i])
+		}
+	}
+	cursor := "0"
+	if len(keys) > count {
+		cursor = hex.EncodeToString([]byte(keys[count]))
+	}
+
+	reply := fmt.Sprintf("*2\r\n$%d\r\n%s\r\n*%d\r\n", len(cursor), cursor, len(matched))
+	for _, key := range matched {
+		reply += fmt.Sprintf("$%d\r\n%s\r\n", len(key), key)
+	}
+	return reply
+}
+
+func (f *fakeRedis) live(key string) (fakeRedisValue, bool) {
+	value, ok := f.data[key]
+	if !ok || time.Now().After(value.expiration) {
+		return fakeRedisValue{}, false
+	}
+	return value, true
+}
+
+func (f *fakeRedis) get(key string) (fakeRedisValue, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.live(key)
+}
+
+func readFakeRedisCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		if line, err = reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+// matchRedisPattern supports the subset of Redis glob patterns used by RedisStore: '*', '?' and backslash escapes
+func matchRedisPattern(pattern string, key string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := len(key); i >= 0; i-- {
+				if matchRedisPattern(pattern[1:], key[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(key) == 0 {
+				return false
+			}
+		case '\\':
+			pattern = pattern[1:]
+			fallthrough
+		default:
+			if len(key) == 0 || len(pattern) == 0 || pattern[0] != key[0] {
+				return false
+			}
+		}
+		pattern, key = pattern[1:], key[1:]
+	}
+	return len(key) == 0
+}
+
+func newTestRedisStore(t *testing.T, server *fakeRedis, config RedisConfig) *RedisStore {
+	t.Helper()
+	config.Addr = server.addr()
+	store, err := NewRedisStore(zap.NewNop(), config)
+	if err != nil {
+		t.Fatalf("NewRedisStore failed: %v", err)
+	}
+	t.Cleanup(store.Close)
+	return store
+}
+
+func TestRedisStoreGet(t *testing.T) {
+	tests := []struct {
+		name          string
+		set           func(s *RedisStore)
+		key           string
+		expected      interface{}
+		expectedFound bool
+	}{
+		{
+			name:          "String",
+			set:           func(s *RedisStore) { s.SetExpiredAfterTimePeriod("key", "value", time.Minute) },
+			key:           "key",
+			expected:      "value",
+			expectedFound: true,
+		},
+		{
+			name:          "Int keeps its type",
+			set:           func(s *RedisStore) { s.SetExpiredAfterTimePeriod("key", 42, time.Minute) },
+			key:           "key",
+			expected:      42,
+			expectedFound: true,
+		},
+		{
+			name: "Registered type",
+			set: func(s *RedisStore) {
+				s.SetExpiredAtTime("key", []price{{Hour: 1, Value: 2.5}}, time.Now().Add(time.Minute))
+			},
+			key:           "key",
+			expected:      []price{{Hour: 1, Value: 2.5}},
+			expectedFound: true,
+		},
+		{
+			name: "Missing key",
+			key:  "missing",
+		},
+		{
+			name: "Expired duration",
+			set:  func(s *RedisStore) { s.SetExpiredAfterTimePeriod("key", "value", -time.Second) },
+			key:  "key",
+		},
+		{
+			name: "Expired time replaces the old value",
+			set: func(s *RedisStore) {
+				s.SetExpiredAfterTimePeriod("key", "old", time.Minute)
+				s.SetExpiredAtTime("key", "new", time.Now().Add(-time.Second))
+			},
+			key: "key",
+		},
+		{
+			name: "Unregistered type is not cached",
+			set:  func(s *RedisStore) { s.SetExpiredAfterTimePeriod("key", struct{ Name string }{"alice"}, time.Minute) },
+			key:  "key",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := newTestRedisStore(t, startFakeRedis(t, ""), RedisConfig{})
+			store.RegisterType("prices", []price{})
+			if test.set != nil {
+				test.set(store)
+			}
+
+			value, found := store.Get(test.key)
+			if found != test.expectedFound || !reflect.DeepEqual(value, test.expected) {
+				t.Errorf("Expected (%#v, %v), got (%#v, %v)", test.expected, test.expectedFound, value, found)
+			}
+		})
+	}
+}
+
+func TestRedisStoreExpiration(t *testing.T) {
+	server := startFakeRedis(t, "")
+	store := newTestRedisStore(t, server, RedisConfig{KeyPrefix: "svc:"})
+	expiration := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+
+	store.SetExpiredAtTime("at", "value", expiration)
+	store.SetExpiredAfterTimePeriod("after", "value", time.Minute)
+
+	if value, ok := server.get("svc:at"); !ok || !value.expiration.Equal(expiration) {
+		t.Errorf("Expected expiration %v, got %v", expiration, value.expiration)
+	}
+	if value, ok := server.get("svc:after"); !ok || time.Until(value.expiration) <= 59*time.Second {
+		t.Errorf("Expected expiration in a minute, got %v", value.expiration)
+	}
+}
+
+func TestRedisStoreTTL(t *testing.T) {
+	store := newTestRedisStore(t, startFakeRedis(t, ""), RedisConfig{KeyPrefix: "svc:"})
+	store.SetExpiredAfterTimePeriod("key", "value", time.Minute)
+
+	if ttl, found := store.TTL("key"); !found || ttl <= 59*time.Second || ttl > time.Minute {
+		t.Errorf("Expected a TTL of about a minute, got (%v, %v)", ttl, found)
+	}
+	if _, found := store.TTL("missing"); found {
+		t.Error("Missing key should have no TTL")
+	}
+}
+
+func TestRedisStoreDelete(t *testing.T) {
+	store := newTestRedisStore(t, startFakeRedis(t, ""), RedisConfig{})
+	store.SetExpiredAfterTimePeriod("key", "value", time.Minute)
+
+	store.Delete("key")
+	store.Delete("missing")
+
+	if _, found := store.Get("key"); found {
+		t.Error("Deleted key should not be found")
+	}
+}
+
+func TestRedisStoreTake(t *testing.T) {
+	server := startFakeRedis(t, "")
+	first := newTestRedisStore(t, server, RedisConfig{KeyPrefix: "svc:"})
+	second := newTestRedisStore(t, server, RedisConfig{KeyPrefix: "svc:"})
+	first.SetExpiredAfterTimePeriod("key", "value", time.Minute)
+
+	if value, found := second.Take("key"); !found || value != "value" {
+		t.Errorf("Expected (value, true), got (%v, %v)", value, found)
+	}
+	if _, found := first.Take("key"); found {
+		t.Error("A taken value should not be taken again, even on another replica")
+	}
+	if _, found := first.Get("key"); found {
+		t.Error("A taken value should be removed")
+	}
+}
+
+func TestRedisStoreDeletePrefix(t *testing.T) {
+	store := newTestRedisStore(t, startFakeRedis(t, ""), RedisConfig{KeyPrefix: "svc:"})
+	// more keys than one SCAN call returns, so the cursor has to be followed
+	for i := 0; i < 3*redisScanCount; i++ {
+		store.SetExpiredAfterTimePeriod(fmt.Sprintf("user:%d", i), i, time.Minute)
+	}
+	store.SetExpiredAfterTimePeriod("post:1", "hello", time.Minute)
+	store.SetExpiredAfterTimePeriod("a*b:1", "glob", time.Minute)
+	store.SetExpiredAfterTimePeriod("axb:1", "literal", time.Minute)
+
+	store.DeletePrefix("user:")
+	store.DeletePrefix("a*b:")
+
+	for i := 0; i < 3*redisScanCount; i++ {
+		if _, found := store.Get(fmt.Sprintf("user:%d", i)); found {
+			t.Fatalf("Key user:%d should have been deleted", i)
+		}
+	}
+	for _, key := range []string{"post:1", "axb:1"} {
+		if _, found := store.Get(key); !found {
+			t.Errorf("Key %s should not have been deleted", key)
+		}
+	}
+}
+
+func TestRedisStoreKeyPrefix(t *testing.T) {
+	server := startFakeRedis(t, "")
+	prices := newTestRedisStore(t, server, RedisConfig{KeyPrefix: "prices:"})
+	users := newTestRedisStore(t, server, RedisConfig{KeyPrefix: "users:"})
+
+	prices.SetExpiredAfterTimePeriod("key", "price", time.Minute)
+	users.SetExpiredAfterTimePeriod("key", "user", time.Minute)
+	users.DeletePrefix("")
+
+	if value, found := prices.Get("key"); !found || value != "price" {
+		t.Errorf("Expected (price, true), got (%v, %v)", value, found)
+	}
+	if _, found := users.Get("key"); found {
+		t.Error("Key of users should have been deleted")
+	}
+}
+
+func TestRedisStoreSharedBetweenReplicas(t *testing.T) {
+	server := startFakeRedis(t, "")
+	first := newTestRedisStore(t, server, RedisConfig{})
+	second := newTestRedisStore(t, server, RedisConfig{})
+
+	first.SetExpiredAfterTimePeriod("key", "value", time.Minute)
+	if value, found := second.Get("key"); !found || value != "value" {
+		t.Errorf("Expected (value, true), got (%v, %v)", value, found)
+	}
+
+	second.Delete("key")
+	if _, found := first.Get("key"); found {
+		t.Error("Deleting on one replica should be seen by the other")
+	}
+}
+
+func TestNewRedisStore(t *testing.T) {
+	server := startFakeRedis(t, "secret")
+	unreachable := startFakeRedis(t, "")
+	unreachable.listener.Close()
+
+	tests := []struct {
+		name        string
+		config      RedisConfig
+		expectError bool
+	}{
+		{name: "Correct password", config: RedisConfig{Addr: server.addr(), Password: "secret", DB: 1}},
+		{name: "Wrong password", config: RedisConfig{Addr: server.addr(), Password: "wrong"}, expectError: true},
+		{name: "Missing password", config: RedisConfig{Addr: server.addr()}, expectError: true},
+		{name: "Missing address", config: RedisConfig{}, expectError: true},
+		{name: "Unreachable server", config: RedisConfig{Addr: unreachable.addr(), DialTimeout: time.Second}, expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store, err := NewRedisStore(zap.NewNop(), test.config)
+			if (err != nil) != test.expectError {
+				t.Fatalf("Expected error=%v, got %v", test.expectError, err)
+			}
+			if store != nil {
+				store.Close()
+			}
+		})
+	}
+}
+
+func TestRedisStoreServerDown(t *testing.T) {
+	server := startFakeRedis(t, "")
+	store := newTestRedisStore(t, server, RedisConfig{Timeout: time.Second})
+	store.SetExpiredAfterTimePeriod("key", "value", time.Minute)
+
+	server.listener.Close()
+	store.Close()
+
+	// a failing store behaves like an empty cache instead of failing the caller
+	store.SetExpiredAfterTimePeriod("key", "value", time.Minute)
+	store.DeletePrefix("")
+	if _, found := store.Get("key"); found {
+		t.Error("Expected a miss when the server cannot be reached")
+	}
+}
+
+func TestNewStore(t *testing.T) {
+	server := startFakeRedis(t, "")
+
+	tests := []struct {
+		name         string
+		config       StoreConfig
+		expectedType Store
+		expectError  bool
+	}{
+		{name: "Default", config: StoreConfig{}, expectedType: &Cache{}},
+		{name: "Memory", config: StoreConfig{Backend: BackendMemory}, expectedType: &Cache{}},
+		{name: "Redis", config: StoreConfig{Backend: BackendRedis, Redis: RedisConfig{Addr: server.addr()}}, expectedType: &RedisStore{}},
+		{name: "Unknown", config: StoreConfig{Backend: "memcached"}, expectError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store, err := NewStore(zap.NewNop(), test.config)
+			if (err != nil) != test.expectError {
+				t.Fatalf("Expected error=%v, got %v", test.expectError, err)
+			}
+			if test.expectError {
+				return
+			}
+			defer store.Close()
+			if reflect.TypeOf(store) != reflect.TypeOf(test.expectedType) {
+				t.Fatalf("Expected %T, got %T", test.expectedType, store)
+			}
+
+			store.SetExpiredAfterTimePeriod("key", "value", time.Minute)
+			if value, found := store.Get("key"); !found || value != "value" {
+				t.Errorf("Expected (value, true), got (%v, %v)", value, found)
+			}
+		})
+	}
+}
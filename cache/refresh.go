@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// KeyLoader fetches the current value of a key. It is registered with RegisterLoader to refresh cached values in the background.
+type KeyLoader[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+// WithRefreshAhead reloads a value in the background when it is read within window before its expiration,
+// so frequently read keys are replaced before they expire and readers never see a cold miss.
+// It only applies to values cached for a duration, e.g. by SetExpiredAfterTimePeriod or SetWithSoftTTL,
+// and requires a loader registered with RegisterLoader.
+func WithRefreshAhead(window time.Duration) Option {
+	return func(o *options) {
+		o.refreshAhead = window
+	}
+}
+
+// RegisterLoader registers the loader used to refresh stale values and values close to their expiration.
+// Refreshed values are cached for the same durations as the values they replace.
+//
+// Example usage:
+//
+//	c.RegisterLoader(func(ctx context.Context, date string) ([]Price, error) {
+//		return client.FetchPrices(ctx, date)
+//	})
+//	c.SetWithSoftTTL(today, prices, 5*time.Minute, time.Hour)
+func (c *Typed[K, V]) RegisterLoader(loader KeyLoader[K, V]) {
+	c.loadLock.Lock()
+	defer c.loadLock.Unlock()
+	c.refresher = loader
+}
+
+// SetWithSoftTTL adds new key-value pair to the cache.
+// After softTTL the value becomes stale: Get still returns it but triggers one asynchronous refresh
+// through the loader registered with RegisterLoader. After hardTTL the value expires.
+func (c *Typed[K, V]) SetWithSoftTTL(key K, value V, softTTL time.Duration, hardTTL time.Duration) {
+	c.lock.Lock()
+	defer c.unlock()
+
+	now := c.clock.Now()
+	c.store(key, Entry[V]{
+		Value:          value,
+		Expiration:     now.Add(hardTTL),
+		SoftExpiration: now.Add(softTTL),
+		ttl:            hardTTL,
+		softTTL:        softTTL,
+	})
+}
+
+// needsRefresh reports whether a live entry is stale or close enough to its expiration to be refreshed
+func (c *Typed[K, V]) needsRefresh(entry Entry[V], now time.Time) bool {
+	if !entry.SoftExpiration.IsZero() && now.After(entry.SoftExpiration) {
+		return true
+	}
+	return c.refreshAhead > 0 && entry.ttl > 0 && entry.Expiration.Sub(now) <= c.refreshAhead
+}
+
+// refresh reloads the entry in the background through the registered loader,
+// unless no loader is registered or the key is already being loaded or refreshed.
+// Refreshes are tracked apart from GetOrLoad calls: a refresh only replaces a live entry,
+// so a GetOrLoad miss must run its own loader and cache the result for its own ttl instead of joining one.
+func (c *Typed[K, V]) refresh(key K, entry Entry[V]) {
+	c.loadLock.Lock()
+	defer c.loadLock.Unlock()
+
+	refresher := c.refresher
+	if refresher == nil {
+		return
+	}
+	if _, inFlight := c.calls[key]; inFlight {
+		return
+	}
+	if _, inFlight := c.refreshes[key]; inFlight {
+		return
+	}
+
+	c.logger.Debug("[go-goods] cache refreshing value in background", zap.Any("key", key))
+	loader := func(ctx context.Context) (V, error) {
+		return refresher(ctx, key)
+	}
+	c.startLoad(context.Background(), c.refreshes, key, loader, c.storeRefreshed(key))
+}
+
+// storeRefreshed returns the function which replaces the value of key with its reloaded value.
+// The entry keeps its tags and maximum expiration, and its durations restart from now.
+// Nothing is stored if the key was deleted or expired while it was reloaded, so a refresh does not revive an invalidated key.
+func (c *Typed[K, V]) storeRefreshed(key K) func(value V) {
+	return func(value V) {
+		c.lock.Lock()
+		defer c.unlock()
+
+		now := c.clock.Now()
+		entry, found := c.live(key, now)
+		if !found {
+			return
+		}
+		entry.Value = value
+		switch {
+		case entry.sliding > 0:
+			entry.Expiration = entry.slidingExpiration(now)
+		case entry.ttl > 0:
+			entry.Expiration = now.Add(entry.ttl)
+		}
+		if !entry.SoftExpiration.IsZero() {
+			entry.SoftExpiration = now.Add(entry.softTTL)
+		}
+		if !entry.MaxExpiration.IsZero() && entry.Expiration.After(entry.MaxExpiration) {
+			entry.Expiration = entry.MaxExpiration
+		}
+		c.modify(key, entry)
+	}
+}
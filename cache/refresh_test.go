@@ -0,0 +1,251 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/clock"
+	"go.uber.org/zap"
+)
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	c := NewTyped[string, int](zap.NewNop(), WithClock(clk))
+	var loads atomic.Int32
+	release := make(chan struct{})
+	c.RegisterLoader(func(ctx context.Context, key string) (int, error) {
+		loads.Add(1)
+		<-release
+		return 2, nil
+	})
+
+	c.SetWithSoftTTL("key", 1, time.Minute, time.Hour)
+	clk.Advance(2 * time.Minute)
+
+	// every read of a stale value returns it immediately, but only one refresh runs
+	for i := 0; i < 10; i++ {
+		if value, found := c.Get("key"); !found || value != 1 {
+			t.Fatalf("Expected stale value (1, true), got (%d, %v)", value, found)
+		}
+	}
+	close(release)
+
+	waitFor(t, func() bool {
+		value, _ := c.Get("key")
+		return value == 2
+	})
+	if n := loads.Load(); n != 1 {
+		t.Errorf("Expected 1 refresh, got %d", n)
+	}
+
+	c.lock.RLock()
+	entry := c.data["key"]
+	c.lock.RUnlock()
+	if entry.softTTL != time.Minute || entry.ttl != time.Hour {
+		t.Errorf("Refreshed value should keep the TTLs, got soft=%v hard=%v", entry.softTTL, entry.ttl)
+	}
+}
+
+func TestSoftTTLFreshValueIsNotRefreshed(t *testing.T) {
+	c := NewTyped[string, int](zap.NewNop())
+	c.RegisterLoader(func(ctx context.Context, key string) (int, error) {
+		t.Error("Loader should not be called for a fresh value")
+		return 0, nil
+	})
+
+	c.SetWithSoftTTL("key", 1, time.Minute, time.Hour)
+	if value, found := c.Get("key"); !found || value != 1 {
+		t.Errorf("Expected (1, true), got (%d, %v)", value, found)
+	}
+	assertNoLoadInFlight(t, c, "key")
+}
+
+func TestSoftTTLWithoutLoader(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	c := NewTyped[string, int](zap.NewNop(), WithClock(clk))
+	c.SetWithSoftTTL("key", 1, time.Minute, time.Hour)
+	clk.Advance(2 * time.Minute)
+
+	if value, found := c.Get("key"); !found || value != 1 {
+		t.Errorf("Stale value should be served without a loader, got (%d, %v)", value, found)
+	}
+	assertNoLoadInFlight(t, c, "key")
+}
+
+func TestSoftTTLHardExpiration(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	c := NewTyped[string, int](zap.NewNop(), WithClock(clk))
+	c.SetWithSoftTTL("key", 1, time.Minute, time.Hour)
+	clk.Advance(2 * time.Hour)
+
+	if _, found := c.Get("key"); found {
+		t.Error("Value past its hard expiration should not be served")
+	}
+}
+
+func TestRefreshFailureKeepsStaleValue(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	c := NewTyped[string, int](zap.NewNop(), WithClock(clk))
+	var loads atomic.Int32
+	c.RegisterLoader(func(ctx context.Context, key string) (int, error) {
+		loads.Add(1)
+		return 0, errors.New("upstream unavailable")
+	})
+
+	c.SetWithSoftTTL("key", 1, time.Minute, time.Hour)
+	clk.Advance(2 * time.Minute)
+	c.Get("key")
+	waitFor(t, func() bool {
+		c.loadLock.Lock()
+		defer c.loadLock.Unlock()
+		return loads.Load() == 1 && len(c.refreshes) == 0
+	})
+
+	if value, found := c.Get("key"); !found || value != 1 {
+		t.Errorf("Stale value should be kept after a failed refresh, got (%d, %v)", value, found)
+	}
+}
+
+func TestRefreshAhead(t *testing.T) {
+	tests := []struct {
+		name          string
+		ttl           time.Duration
+		expectRefresh bool
+	}{
+		{
+			name:          "Refresh value close to expiration",
+			ttl:           time.Second,
+			expectRefresh: true,
+		},
+		{
+			name:          "Do not refresh value far from expiration",
+			ttl:           time.Hour,
+			expectRefresh: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := NewTyped[string, int](zap.NewNop(), WithRefreshAhead(time.Minute))
+			refreshed := make(chan struct{})
+			c.RegisterLoader(func(ctx context.Context, key string) (int, error) {
+				close(refreshed)
+				return 2, nil
+			})
+
+			c.SetExpiredAfterTimePeriod("key", 1, test.ttl)
+			if value, found := c.Get("key"); !found || value != 1 {
+				t.Fatalf("Expected current value (1, true), got (%d, %v)", value, found)
+			}
+
+			select {
+			case <-refreshed:
+				if !test.expectRefresh {
+					t.Error("Value should not have been refreshed")
+				}
+			case <-time.After(50 * time.Millisecond):
+				if test.expectRefresh {
+					t.Error("Value should have been refreshed ahead of its expiration")
+				}
+			}
+		})
+	}
+}
+
+// waitFor polls condition until it holds or fails the test after a timeout
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func assertNoLoadInFlight[K comparable, V any](t *testing.T, c *Typed[K, V], key K) {
+	t.Helper()
+	c.loadLock.Lock()
+	defer c.loadLock.Unlock()
+	if _, inFlight := c.calls[key]; inFlight {
+		t.Errorf("Key %v should not be loading", key)
+	}
+	if _, inFlight := c.refreshes[key]; inFlight {
+		t.Errorf("Key %v should not be refreshing", key)
+	}
+}
+
+func TestRefreshKeepsTags(t *testing.T) {
+	tests := []struct {
+		name string
+		set  func(c *Typed[string, int])
+	}{
+		{
+			name: "Refresh ahead of expiration",
+			set: func(c *Typed[string, int]) {
+				c.SetWithTags("key", 1, time.Second, "user:1")
+			},
+		},
+		{
+			name: "Stale while revalidate",
+			set: func(c *Typed[string, int]) {
+				c.SetWithSoftTTL("key", 1, time.Millisecond, time.Hour)
+				c.lock.Lock()
+				c.tag("key", []string{"user:1"})
+				c.lock.Unlock()
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Now())
+			c := NewTyped[string, int](zap.NewNop(), WithClock(clk), WithRefreshAhead(time.Minute))
+			c.RegisterLoader(func(ctx context.Context, key string) (int, error) {
+				return 2, nil
+			})
+
+			test.set(c)
+			clk.Advance(10 * time.Millisecond)
+			c.Get("key")
+			waitFor(t, func() bool {
+				value, _ := c.Peek("key")
+				return value == 2
+			})
+
+			c.InvalidateTag("user:1")
+			if _, found := c.Get("key"); found {
+				t.Error("Refreshed value should keep its tags and be removed by InvalidateTag")
+			}
+		})
+	}
+}
+
+func TestRefreshDoesNotReviveDeletedKey(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	c := NewTyped[string, int](zap.NewNop(), WithClock(clk))
+	release := make(chan struct{})
+	c.RegisterLoader(func(ctx context.Context, key string) (int, error) {
+		<-release
+		return 2, nil
+	})
+
+	c.SetWithSoftTTL("key", 1, time.Minute, time.Hour)
+	clk.Advance(2 * time.Minute)
+	c.Get("key")
+	c.Delete("key")
+	close(release)
+
+	waitFor(t, func() bool {
+		c.loadLock.Lock()
+		defer c.loadLock.Unlock()
+		return len(c.refreshes) == 0
+	})
+	if value, found := c.Get("key"); found {
+		t.Errorf("Key deleted while it was refreshed should stay deleted, got %d", value)
+	}
+}
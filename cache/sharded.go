@@ -0,0 +1,373 @@
+package cache
+
+import (
+	"context"
+	"hash/maphash"
+	"io"
+	"iter"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Sharded represents a thread-safe in-memory cache split into independent shards.
+// Every key is hashed to one shard and each shard has its own read-write lock,
+// so concurrent operations on different keys rarely wait for each other.
+// It provides the same API as Typed.
+type Sharded[K comparable, V any] struct {
+	shards  []*Typed[K, V]
+	seed    maphash.Seed
+	janitor *janitor // nil when the janitor is disabled
+}
+
+// NewSharded returns a new Sharded cache with the given number of shards.
+// Options apply to every shard, except that WithMaxEntries and WithMaxBytes are the capacity of the whole cache
+// and are divided between the shards, and a single janitor goroutine serves all shards.
+//
+// Example usage:
+//
+//	c := cache.NewSharded[string, []Price](logger, runtime.GOMAXPROCS(0)*4)
+func NewSharded[K comparable, V any](logger *zap.Logger, shards int, opts ...Option) *Sharded[K, V] {
+	o := newOptions(opts)
+	if shards < 1 {
+		shards = 1
+	}
+
+	shardOptions := o
+	shardOptions.janitorInterval = 0
+	if o.maxEntries > 0 {
+		shardOptions.maxEntries = (o.maxEntries + shards - 1) / shards
+	}
+	if o.maxBytes > 0 {
+		shardOptions.maxBytes = (o.maxBytes + int64(shards) - 1) / int64(shards)
+	}
+
+	c := &Sharded[K, V]{
+		shards: make([]*Typed[K, V], shards),
+		seed:   maphash.MakeSeed(),
+	}
+	for i := range c.shards {
+		c.shards[i] = newTyped[K, V](logger, make(map[K]Entry[V]), shardOptions)
+	}
+	if o.janitorInterval > 0 {
+		c.janitor = startJanitor(o.janitorCtx, o.janitorInterval, logger, c.purgeExpired)
+	}
+	return c
+}
+
+// SetExpiredAfterTimePeriod adds new key-value pair to the cache.
+// The value expires after the given duration counted from now.
+func (c *Sharded[K, V]) SetExpiredAfterTimePeriod(key K, value V, duration time.Duration) {
+	c.shard(key).SetExpiredAfterTimePeriod(key, value, duration)
+}
+
+// SetExpiredAtTime adds new key-value pair to the cache.
+// The value expires at the given point in time.
+func (c *Sharded[K, V]) SetExpiredAtTime(key K, value V, expiredTime time.Time) {
+	c.shard(key).SetExpiredAtTime(key, value, expiredTime)
+}
+
+// Get retrieves a value from the cache by using a key.
+// If the value is missing or expired, it returns the zero value of V and `false`.
+func (c *Sharded[K, V]) Get(key K) (V, bool) {
+	return c.shard(key).Get(key)
+}
+
+// GetOrLoad returns the cached value of key. On a miss it calls loader and caches its result for ttl.
+// See Typed.GetOrLoad for details.
+func (c *Sharded[K, V]) GetOrLoad(ctx context.Context, key K, ttl time.Duration, loader Loader[V]) (V, error) {
+	return c.shard(key).GetOrLoad(ctx, key, ttl, loader)
+}
+
+// SetWithSoftTTL adds new key-value pair to the cache which becomes stale after softTTL and expires after hardTTL.
+// See Typed.SetWithSoftTTL for details.
+func (c *Sharded[K, V]) SetWithSoftTTL(key K, value V, softTTL time.Duration, hardTTL time.Duration) {
+	c.shard(key).SetWithSoftTTL(key, value, softTTL, hardTTL)
+}
+
+// SetWithSlidingExpiration adds new key-value pair to the cache which expires after being idle for idleTTL,
+// but not later than maxLifetime from now. See Typed.SetWithSlidingExpiration for details.
+func (c *Sharded[K, V]) SetWithSlidingExpiration(key K, value V, idleTTL time.Duration, maxLifetime time.Duration) {
+	c.shard(key).SetWithSlidingExpiration(key, value, idleTTL, maxLifetime)
+}
+
+// Increment atomically adds delta to the numeric value of key and returns the result.
+// See Typed.Increment for details.
+func (c *Sharded[K, V]) Increment(key K, delta int64, ttl time.Duration) (V, error) {
+	return c.shard(key).Increment(key, delta, ttl)
+}
+
+// Decrement atomically subtracts delta from the numeric value of key and returns the result.
+// See Typed.Increment for details.
+func (c *Sharded[K, V]) Decrement(key K, delta int64, ttl time.Duration) (V, error) {
+	return c.shard(key).Decrement(key, delta, ttl)
+}
+
+// CompareAndSwap atomically replaces the value of key with new if its current value is deeply equal to old.
+// See Typed.CompareAndSwap for details.
+func (c *Sharded[K, V]) CompareAndSwap(key K, old V, new V) bool {
+	return c.shard(key).CompareAndSwap(key, old, new)
+}
+
+// SetIfAbsent caches the value for ttl only if key has no live value yet, and reports whether it was stored.
+func (c *Sharded[K, V]) SetIfAbsent(key K, value V, ttl time.Duration) bool {
+	return c.shard(key).SetIfAbsent(key, value, ttl)
+}
+
+// Take atomically returns and removes the live value of key. See Typed.Take for details.
+func (c *Sharded[K, V]) Take(key K) (V, bool) {
+	return c.shard(key).Take(key)
+}
+
+// Update atomically replaces the value of key with the result of fn. See Typed.Update for details.
+func (c *Sharded[K, V]) Update(key K, ttl time.Duration, fn func(current V, found bool) V) V {
+	return c.shard(key).Update(key, ttl, fn)
+}
+
+// RegisterLoader registers the loader used to refresh stale values on every shard.
+// See Typed.RegisterLoader for details.
+func (c *Sharded[K, V]) RegisterLoader(loader KeyLoader[K, V]) {
+	for _, shard := range c.shards {
+		shard.RegisterLoader(loader)
+	}
+}
+
+// Delete cache based on receiving cache key. If key is not valid, then Delete is no-op
+func (c *Sharded[K, V]) Delete(key K) {
+	c.shard(key).Delete(key)
+}
+
+// DeleteFunc removes all cache entries whose key matches the given predicate.
+// Shards are processed one after another, so only one shard is locked at a time.
+func (c *Sharded[K, V]) DeleteFunc(match func(key K) bool) {
+	for _, shard := range c.shards {
+		shard.DeleteFunc(match)
+	}
+}
+
+// OnEvict registers a callback which is called whenever an entry leaves any shard.
+// See Typed.OnEvict for details.
+func (c *Sharded[K, V]) OnEvict(callback EvictionCallback[K, V]) {
+	for _, shard := range c.shards {
+		shard.OnEvict(callback)
+	}
+}
+
+// OnExpire registers a callback which is called whenever an entry of any shard expires.
+// See Typed.OnExpire for details.
+func (c *Sharded[K, V]) OnExpire(callback EvictionCallback[K, V]) {
+	for _, shard := range c.shards {
+		shard.OnExpire(callback)
+	}
+}
+
+// SetWithTags adds new key-value pair to the cache which expires after ttl and belongs to the given tags.
+// See Typed.SetWithTags for details.
+func (c *Sharded[K, V]) SetWithTags(key K, value V, ttl time.Duration, tags ...string) {
+	c.shard(key).SetWithTags(key, value, ttl, tags...)
+}
+
+// InvalidateTag removes all cache entries which were set with the given tag from every shard.
+func (c *Sharded[K, V]) InvalidateTag(tag string) {
+	for _, shard := range c.shards {
+		shard.InvalidateTag(tag)
+	}
+}
+
+// DeletePrefix removes all cache entries whose key starts with prefix from every shard.
+// See Typed.DeletePrefix for details.
+func (c *Sharded[K, V]) DeletePrefix(prefix string) {
+	for _, shard := range c.shards {
+		shard.DeletePrefix(prefix)
+	}
+}
+
+// SetCodec sets the codec used to encode values in snapshots on every shard.
+func (c *Sharded[K, V]) SetCodec(codec Codec[V]) {
+	for _, shard := range c.shards {
+		shard.SetCodec(codec)
+	}
+}
+
+// Snapshot writes all entries of every shard which have not expired yet to w.
+// See Typed.Snapshot for details.
+func (c *Sharded[K, V]) Snapshot(w io.Writer) error {
+	codec, key := c.shards[0].snapshotSettings()
+
+	now := c.shards[0].clock.Now()
+	var entries []keyedEntry[K, V]
+	for _, shard := range c.shards {
+		entries = append(entries, shard.liveEntries(now)...)
+	}
+	return writeSnapshot(w, entries, codec, key)
+}
+
+// Restore adds the entries of a snapshot written by Snapshot to the shards owning their keys.
+// See Typed.Restore for details.
+func (c *Sharded[K, V]) Restore(r io.Reader) error {
+	codec, key := c.shards[0].snapshotSettings()
+
+	entries, err := readSnapshot[K](r, codec, key)
+	if err != nil {
+		return err
+	}
+
+	byShard := make(map[*Typed[K, V]][]keyedEntry[K, V])
+	for _, e := range entries {
+		shard := c.shard(e.key)
+		byShard[shard] = append(byShard[shard], e)
+	}
+	now := c.shards[0].clock.Now()
+	for shard, shardEntries := range byShard {
+		shard.restore(shardEntries, now)
+	}
+	return nil
+}
+
+// All returns an iterator over the key-value pairs of all shards which have not expired yet. See Typed.All for details.
+func (c *Sharded[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, shard := range c.shards {
+			for key, value := range shard.All() {
+				if !yield(key, value) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the keys of all shards whose values have not expired yet
+func (c *Sharded[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for key := range c.All() {
+			if !yield(key) {
+				return
+			}
+		}
+	}
+}
+
+// Len returns the number of values in all shards which have not expired yet
+func (c *Sharded[K, V]) Len() int {
+	n := 0
+	for _, shard := range c.shards {
+		n += shard.Len()
+	}
+	return n
+}
+
+// Peek returns the value of key from its shard without side effects. See Typed.Peek for details.
+func (c *Sharded[K, V]) Peek(key K) (V, bool) {
+	return c.shard(key).Peek(key)
+}
+
+// TTL returns how long the value of key stays in its shard, and `false` if it is missing or expired
+func (c *Sharded[K, V]) TTL(key K) (time.Duration, bool) {
+	return c.shard(key).TTL(key)
+}
+
+// GetMany retrieves the values of keys from their shards, leaving missing keys out of the result
+func (c *Sharded[K, V]) GetMany(keys ...K) map[K]V {
+	values := make(map[K]V, len(keys))
+	for _, key := range keys {
+		if value, found := c.shard(key).Get(key); found {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// SetMany adds all key-value pairs of values to their shards, locking each shard once
+func (c *Sharded[K, V]) SetMany(values map[K]V, duration time.Duration) {
+	byShard := make(map[*Typed[K, V]]map[K]V)
+	for key, value := range values {
+		shard := c.shard(key)
+		if byShard[shard] == nil {
+			byShard[shard] = make(map[K]V)
+		}
+		byShard[shard][key] = value
+	}
+	for shard, shardValues := range byShard {
+		shard.SetMany(shardValues, duration)
+	}
+}
+
+// DeleteMany removes the values of keys from their shards, locking each shard once
+func (c *Sharded[K, V]) DeleteMany(keys ...K) {
+	byShard := make(map[*Typed[K, V]][]K)
+	for _, key := range keys {
+		shard := c.shard(key)
+		byShard[shard] = append(byShard[shard], key)
+	}
+	for shard, shardKeys := range byShard {
+		shard.DeleteMany(shardKeys...)
+	}
+}
+
+// Stats returns the statistics of all shards added together
+func (c *Sharded[K, V]) Stats() Stats {
+	var total Stats
+	for _, shard := range c.shards {
+		stats := shard.Stats()
+		total.Hits += stats.Hits
+		total.Misses += stats.Misses
+		total.Expirations += stats.Expirations
+		total.Evictions += stats.Evictions
+		total.Size += stats.Size
+		total.Bytes += stats.Bytes
+	}
+	return total
+}
+
+// Close stops the background janitor, if any, and waits for it to exit. It is safe to call Close more than once.
+func (c *Sharded[K, V]) Close() {
+	c.janitor.close()
+}
+
+// purgeExpired removes every expired entry from all shards and returns how many were removed
+func (c *Sharded[K, V]) purgeExpired() int {
+	purged := 0
+	for _, shard := range c.shards {
+		purged += shard.purgeExpired()
+	}
+	return purged
+}
+
+// shard returns the shard owning the key
+func (c *Sharded[K, V]) shard(key K) *Typed[K, V] {
+	return c.shards[maphash.Comparable(c.seed, key)%uint64(len(c.shards))]
+}
+
+// ShardedCache is the sharded counterpart of Cache: a Sharded cache with string keys and interface{} values.
+type ShardedCache struct {
+	*Sharded[string, interface{}]
+	types *TypeCodec
+}
+
+// NewShardedCache returns a new ShardedCache instance with the given number of shards
+func NewShardedCache(logger *zap.Logger, shards int, opts ...Option) *ShardedCache {
+	c := &ShardedCache{
+		Sharded: NewSharded[string, interface{}](logger, shards, opts...),
+		types:   NewTypeCodec(),
+	}
+	c.SetCodec(c.types)
+	return c
+}
+
+// RegisterType registers the type of sample under name in the default codec of the cache.
+// See Cache.RegisterType for details.
+func (c *ShardedCache) RegisterType(name string, sample interface{}) {
+	c.types.Register(name, sample)
+}
+
+// DeleteAll removes all cache entries that contain the specified key as a substring.
+//
+// Deprecated: DeleteAll scans every entry and also removes keys which only contain key in the middle.
+// Use DeletePrefix or InvalidateTag instead.
+func (c *ShardedCache) DeleteAll(key string) {
+	c.DeleteFunc(func(k string) bool {
+		return strings.Contains(k, key)
+	})
+}
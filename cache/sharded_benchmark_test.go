@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// benchmarkStore is the part of the API shared by Cache and ShardedCache which the parallel benchmarks exercise
+type benchmarkStore interface {
+	SetExpiredAfterTimePeriod(key string, value interface{}, duration time.Duration)
+	Get(key string) (interface{}, bool)
+}
+
+func benchmarkParallel(b *testing.B, c benchmarkStore, writeEvery int) {
+	keys := make([]string, 10_000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key:%d", i)
+		c.SetExpiredAfterTimePeriod(keys[i], "value", time.Hour)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[i%len(keys)]
+			if writeEvery > 0 && i%writeEvery == 0 {
+				c.SetExpiredAfterTimePeriod(key, "value", time.Hour)
+			} else {
+				c.Get(key)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkParallelCacheRead(b *testing.B) {
+	benchmarkParallel(b, NewCache(zap.NewNop()), 0)
+}
+
+func BenchmarkParallelShardedCacheRead(b *testing.B) {
+	benchmarkParallel(b, NewShardedCache(zap.NewNop(), runtime.GOMAXPROCS(0)*4), 0)
+}
+
+func BenchmarkParallelCacheMixed(b *testing.B) {
+	benchmarkParallel(b, NewCache(zap.NewNop()), 10)
+}
+
+func BenchmarkParallelShardedCacheMixed(b *testing.B) {
+	benchmarkParallel(b, NewShardedCache(zap.NewNop(), runtime.GOMAXPROCS(0)*4), 10)
+}
+
+func BenchmarkParallelCacheWrite(b *testing.B) {
+	benchmarkParallel(b, NewCache(zap.NewNop()), 1)
+}
+
+func BenchmarkParallelShardedCacheWrite(b *testing.B) {
+	benchmarkParallel(b, NewShardedCache(zap.NewNop(), runtime.GOMAXPROCS(0)*4), 1)
+}
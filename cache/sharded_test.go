@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestShardedCache(t *testing.T) {
+	c := NewShardedCache(zap.NewNop(), 8)
+
+	c.SetExpiredAfterTimePeriod("user:1", "alice", time.Minute)
+	c.SetExpiredAtTime("user:2", "bob", time.Now().Add(time.Minute))
+	c.SetExpiredAfterTimePeriod("post:1", "hello", time.Minute)
+	c.SetExpiredAfterTimePeriod("expired", "old", -time.Minute)
+
+	tests := []struct {
+		name           string
+		key            string
+		expectedValue  interface{}
+		expectedExists bool
+	}{
+		{name: "Get value set with duration", key: "user:1", expectedValue: "alice", expectedExists: true},
+		{name: "Get value set with time", key: "user:2", expectedValue: "bob", expectedExists: true},
+		{name: "Get expired value", key: "expired", expectedExists: false},
+		{name: "Get non-existent key", key: "nonexistent", expectedExists: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, exists := c.Get(test.key)
+			if exists != test.expectedExists {
+				t.Fatalf("Expected exists=%v, got %v", test.expectedExists, exists)
+			}
+			if exists && value != test.expectedValue {
+				t.Errorf("Expected value %v, got %v", test.expectedValue, value)
+			}
+		})
+	}
+
+	c.Delete("post:1")
+	if _, exists := c.Get("post:1"); exists {
+		t.Error("Key post:1 should have been deleted")
+	}
+
+	c.DeleteAll("user")
+	for _, key := range []string{"user:1", "user:2"} {
+		if _, exists := c.Get(key); exists {
+			t.Errorf("Key %q should have been deleted by DeleteAll", key)
+		}
+	}
+}
+
+func TestShardedDistributesKeys(t *testing.T) {
+	c := NewSharded[int, int](zap.NewNop(), 4)
+	for i := 0; i < 1000; i++ {
+		c.SetExpiredAfterTimePeriod(i, i, time.Minute)
+	}
+
+	total := 0
+	for i, shard := range c.shards {
+		if len(shard.data) == 0 {
+			t.Errorf("Shard %d did not receive any key", i)
+		}
+		total += len(shard.data)
+	}
+	if total != 1000 {
+		t.Errorf("Expected 1000 entries, got %d", total)
+	}
+}
+
+func TestShardedMaxEntries(t *testing.T) {
+	c := NewSharded[int, int](zap.NewNop(), 4, WithMaxEntries(100))
+	for i := 0; i < 1000; i++ {
+		c.SetExpiredAfterTimePeriod(i, i, time.Minute)
+	}
+
+	for i, shard := range c.shards {
+		if len(shard.data) > 25 {
+			t.Errorf("Shard %d holds %d entries, expected at most 25", i, len(shard.data))
+		}
+	}
+}
+
+func TestShardedJanitor(t *testing.T) {
+	c := NewSharded[int, int](zap.NewNop(), 4, WithJanitor(context.Background(), time.Hour))
+	defer c.Close()
+
+	for i, shard := range c.shards {
+		if shard.janitor != nil {
+			t.Errorf("Shard %d should not run its own janitor", i)
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		c.SetExpiredAfterTimePeriod(i, i, -time.Minute)
+	}
+	if purged := c.purgeExpired(); purged != 100 {
+		t.Errorf("Expected 100 purged entries, got %d", purged)
+	}
+}
+
+func TestShardedConcurrentAccess(t *testing.T) {
+	c := NewShardedCache(zap.NewNop(), 16)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := fmt.Sprintf("key:%d", i%100)
+				c.SetExpiredAfterTimePeriod(key, g, time.Minute)
+				c.Get(key)
+				if i%10 == 0 {
+					c.Delete(key)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
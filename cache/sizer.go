@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"reflect"
+)
+
+// Sizer estimates how many bytes a cached value occupies. It is used by caches bounded with WithMaxBytes.
+type Sizer interface {
+	Size(value any) int64
+}
+
+// SizerFunc adapts a function to a Sizer
+//
+// Example usage:
+//
+//	sizer := cache.SizerFunc(func(value any) int64 {
+//		prices := value.([]Price)
+//		return int64(len(prices)) * int64(unsafe.Sizeof(Price{}))
+//	})
+type SizerFunc func(value any) int64
+
+// Size returns f(value)
+func (f SizerFunc) Size(value any) int64 {
+	return f(value)
+}
+
+// Sized is implemented by values which know their own size, so DefaultSizer can measure them
+type Sized interface {
+	CacheSize() int64
+}
+
+// DefaultSizer measures []byte and strings by their length, values implementing Sized by CacheSize,
+// other slices by the sum of the sizes of their element type, e.g. len(prices) * unsafe.Sizeof(Price{}),
+// and any other value by the size of its type.
+// It ignores memory referenced through pointers, maps, strings and slices held inside a value,
+// e.g. the names of a []Price whose Price has a Name string field.
+// Use WithSizer with a custom Sizer to measure such values accurately.
+var DefaultSizer Sizer = SizerFunc(defaultSize)
+
+func defaultSize(value any) int64 {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case Sized:
+		return v.CacheSize()
+	case []byte:
+		return int64(len(v))
+	case string:
+		return int64(len(v))
+	case []string:
+		var size int64
+		for _, s := range v {
+			size += int64(len(s))
+		}
+		return size
+	default:
+		rv := reflect.ValueOf(value)
+		if rv.Kind() == reflect.Slice {
+			return int64(rv.Len()) * int64(rv.Type().Elem().Size())
+		}
+		return int64(rv.Type().Size())
+	}
+}
+
+// WithMaxBytes bounds the total size of the values kept in the cache, as measured by the Sizer set by WithSizer or DefaultSizer.
+// When a value would exceed the budget, entries are evicted according to the configured EvictionPolicy until it fits.
+// A value larger than the whole budget is not cached. A value <= 0 means the size is unbounded, which is the default.
+//
+// The budget is only as accurate as the Sizer: DefaultSizer counts the elements of a slice of structs,
+// but not the strings, slices, maps or pointers inside them, so such values need a custom Sizer.
+//
+// It can be combined with WithMaxEntries. TinyLFU sizes its segments from WithMaxEntries,
+// so without it TinyLFU degrades to evicting the least recently used entry.
+func WithMaxBytes(maxBytes int64) Option {
+	return func(o *options) {
+		o.maxBytes = maxBytes
+	}
+}
+
+// WithSizer sets the Sizer measuring values for WithMaxBytes. Default is DefaultSizer.
+func WithSizer(sizer Sizer) Option {
+	return func(o *options) {
+		o.sizer = sizer
+	}
+}
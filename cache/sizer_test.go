@@ -0,0 +1,191 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type sizedValue int64
+
+func (s sizedValue) CacheSize() int64 {
+	return int64(s)
+}
+
+func TestDefaultSizer(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    any
+		expected int64
+	}{
+		{name: "Nil", value: nil, expected: 0},
+		{name: "Bytes", value: []byte("hello"), expected: 5},
+		{name: "String", value: "hello", expected: 5},
+		{name: "Strings", value: []string{"hello", "world!"}, expected: 11},
+		{name: "Sized", value: sizedValue(1024), expected: 1024},
+		{name: "Int64", value: int64(1), expected: 8},
+		{name: "Struct", value: price{}, expected: 16},
+		{name: "Slice of structs", value: []price{{}, {}, {}}, expected: 48},
+		{name: "Slice of integers", value: []int64{1, 2, 3, 4}, expected: 32},
+		{name: "Empty slice", value: []price{}, expected: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if size := DefaultSizer.Size(test.value); size != test.expected {
+				t.Errorf("Expected %d, got %d", test.expected, size)
+			}
+		})
+	}
+}
+
+func TestMaxBytes(t *testing.T) {
+	tests := []struct {
+		name            string
+		options         []Option
+		act             func(c *Typed[string, string])
+		expectedPresent []string
+		expectedAbsent  []string
+		expectedBytes   int64
+	}{
+		{
+			name:    "Evicts the least recently used values until the new one fits",
+			options: []Option{WithMaxBytes(10)},
+			act: func(c *Typed[string, string]) {
+				c.SetExpiredAfterTimePeriod("a", "aaaa", time.Minute)
+				c.SetExpiredAfterTimePeriod("b", "bbbb", time.Minute)
+				c.Get("a")
+				c.SetExpiredAfterTimePeriod("c", "cccc", time.Minute)
+			},
+			expectedPresent: []string{"a", "c"},
+			expectedAbsent:  []string{"b"},
+			expectedBytes:   8,
+		},
+		{
+			name:    "One large value evicts several small ones",
+			options: []Option{WithMaxBytes(10)},
+			act: func(c *Typed[string, string]) {
+				c.SetExpiredAfterTimePeriod("a", "aaa", time.Minute)
+				c.SetExpiredAfterTimePeriod("b", "bbb", time.Minute)
+				c.SetExpiredAfterTimePeriod("c", "ccc", time.Minute)
+				c.SetExpiredAfterTimePeriod("d", "dddddddd", time.Minute)
+			},
+			expectedPresent: []string{"d"},
+			expectedAbsent:  []string{"a", "b", "c"},
+			expectedBytes:   8,
+		},
+		{
+			name:    "Growing the least recently used value keeps it",
+			options: []Option{WithMaxBytes(10)},
+			act: func(c *Typed[string, string]) {
+				c.SetExpiredAfterTimePeriod("a", "aaaa", time.Minute)
+				c.SetExpiredAfterTimePeriod("b", "bbbb", time.Minute)
+				c.SetExpiredAfterTimePeriod("a", "aaaaaaa", time.Minute)
+				c.SetExpiredAfterTimePeriod("c", "ccc", time.Minute)
+			},
+			expectedPresent: []string{"a", "c"},
+			expectedAbsent:  []string{"b"},
+			expectedBytes:   10,
+		},
+		{
+			name:    "Value larger than the cache is not stored",
+			options: []Option{WithMaxBytes(10)},
+			act: func(c *Typed[string, string]) {
+				c.SetExpiredAfterTimePeriod("a", "aaaa", time.Minute)
+				c.SetExpiredAfterTimePeriod("b", "bbbb", time.Minute)
+				c.SetExpiredAfterTimePeriod("b", strings.Repeat("b", 11), time.Minute)
+			},
+			expectedPresent: []string{"a"},
+			expectedAbsent:  []string{"b"},
+			expectedBytes:   4,
+		},
+		{
+			name:    "Deletes release bytes",
+			options: []Option{WithMaxBytes(10)},
+			act: func(c *Typed[string, string]) {
+				c.SetExpiredAfterTimePeriod("a", "aaaa", time.Minute)
+				c.SetExpiredAfterTimePeriod("b", "bbbb", time.Minute)
+				c.Delete("a")
+				c.SetExpiredAfterTimePeriod("c", "cccc", time.Minute)
+			},
+			expectedPresent: []string{"b", "c"},
+			expectedAbsent:  []string{"a"},
+			expectedBytes:   8,
+		},
+		{
+			name:    "Entry limit still applies",
+			options: []Option{WithMaxBytes(100), WithMaxEntries(2)},
+			act: func(c *Typed[string, string]) {
+				c.SetExpiredAfterTimePeriod("a", "a", time.Minute)
+				c.SetExpiredAfterTimePeriod("b", "b", time.Minute)
+				c.SetExpiredAfterTimePeriod("c", "c", time.Minute)
+			},
+			expectedPresent: []string{"b", "c"},
+			expectedAbsent:  []string{"a"},
+			expectedBytes:   2,
+		},
+		{
+			name:    "LFU keeps frequently used values",
+			options: []Option{WithMaxBytes(10), WithEvictionPolicy(LFU)},
+			act: func(c *Typed[string, string]) {
+				c.SetExpiredAfterTimePeriod("a", "aaaa", time.Minute)
+				c.SetExpiredAfterTimePeriod("b", "bbbb", time.Minute)
+				c.Get("b")
+				c.Get("a")
+				c.Get("a")
+				c.SetExpiredAfterTimePeriod("c", "cccc", time.Minute)
+			},
+			expectedPresent: []string{"a", "c"},
+			expectedAbsent:  []string{"b"},
+			expectedBytes:   8,
+		},
+		{
+			name:    "Custom sizer",
+			options: []Option{WithMaxBytes(100), WithSizer(SizerFunc(func(value any) int64 { return 40 }))},
+			act: func(c *Typed[string, string]) {
+				c.SetExpiredAfterTimePeriod("a", "a", time.Minute)
+				c.SetExpiredAfterTimePeriod("b", "b", time.Minute)
+				c.SetExpiredAfterTimePeriod("c", "c", time.Minute)
+			},
+			expectedPresent: []string{"b", "c"},
+			expectedAbsent:  []string{"a"},
+			expectedBytes:   80,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := NewTyped[string, string](zap.NewNop(), test.options...)
+			test.act(c)
+
+			assertKeys(t, c, test.expectedPresent, test.expectedAbsent)
+			if stats := c.Stats(); stats.Bytes != test.expectedBytes {
+				t.Errorf("Expected %d bytes, got %d", test.expectedBytes, stats.Bytes)
+			}
+		})
+	}
+}
+
+func TestMaxBytesReplacedValueIsNotReportedAsEvicted(t *testing.T) {
+	c := NewCache(zap.NewNop(), WithMaxBytes(10))
+	evicted, _ := recordEvictions(c)
+
+	c.SetExpiredAfterTimePeriod("a", "aaaa", time.Minute)
+	c.SetExpiredAfterTimePeriod("b", "bbbb", time.Minute)
+	c.SetExpiredAfterTimePeriod("a", "aaaaaaa", time.Minute)
+
+	assertEvictions(t, "OnEvict", evicted(), []eviction{{"b", "bbbb", ReasonCapacity}})
+}
+
+func TestShardedMaxBytes(t *testing.T) {
+	c := NewShardedCache(zap.NewNop(), 4, WithMaxBytes(40))
+	for i := 0; i < 100; i++ {
+		c.SetExpiredAfterTimePeriod(strings.Repeat("k", i+1), "aaaa", time.Minute)
+	}
+
+	if stats := c.Stats(); stats.Bytes > 40 || stats.Bytes != int64(stats.Size)*4 {
+		t.Errorf("Expected at most 40 bytes in total, got %+v", stats)
+	}
+}
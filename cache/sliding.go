@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"time"
+)
+
+// SetWithSlidingExpiration adds new key-value pair to the cache which expires after being idle for idleTTL:
+// every Get which finds the value moves its expiration to idleTTL from then.
+// The expiration is never moved past maxLifetime counted from now, so a value read continuously still expires eventually.
+// A maxLifetime <= 0 means the value has no maximum lifetime.
+//
+// Example usage:
+//
+//	// the session expires after 30 minutes without requests, and after 12 hours at the latest
+//	sessions.SetWithSlidingExpiration(sessionID, session, 30*time.Minute, 12*time.Hour)
+func (c *Typed[K, V]) SetWithSlidingExpiration(key K, value V, idleTTL time.Duration, maxLifetime time.Duration) {
+	c.lock.Lock()
+	defer c.unlock()
+
+	now := c.clock.Now()
+	entry := Entry[V]{
+		Value:   value,
+		sliding: idleTTL,
+	}
+	if maxLifetime > 0 {
+		entry.MaxExpiration = now.Add(maxLifetime)
+	}
+	entry.Expiration = entry.slidingExpiration(now)
+	c.store(key, entry)
+}
+
+// slidingExpiration returns the expiration of a sliding entry read at now: idle TTL from now, capped by its maximum expiration
+func (e Entry[V]) slidingExpiration(now time.Time) time.Time {
+	expiration := now.Add(e.sliding)
+	if !e.MaxExpiration.IsZero() && expiration.After(e.MaxExpiration) {
+		return e.MaxExpiration
+	}
+	return expiration
+}
+
+// slide extends the expiration of a live sliding entry which was just read. The caller must hold the lock.
+func (c *Typed[K, V]) slide(key K, entry Entry[V], now time.Time) Entry[V] {
+	entry.Expiration = entry.slidingExpiration(now)
+	c.data[key] = entry
+	c.expirations.set(key, entry.Expiration)
+	return entry
+}
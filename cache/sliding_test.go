@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/clock"
+	"go.uber.org/zap"
+)
+
+func TestSlidingExpiration(t *testing.T) {
+	tests := []struct {
+		name               string
+		options            []Option
+		idleTTL            time.Duration
+		maxLifetime        time.Duration
+		age                time.Duration
+		expectedFound      bool
+		expectedExpiration time.Duration // expected expiration after Get, counted from now
+	}{
+		{
+			name:               "Get extends the expiration",
+			idleTTL:            time.Minute,
+			maxLifetime:        time.Hour,
+			age:                50 * time.Second,
+			expectedFound:      true,
+			expectedExpiration: time.Minute,
+		},
+		{
+			name:               "Get extends the expiration in a bounded cache",
+			options:            []Option{WithMaxEntries(10)},
+			idleTTL:            time.Minute,
+			maxLifetime:        time.Hour,
+			age:                50 * time.Second,
+			expectedFound:      true,
+			expectedExpiration: time.Minute,
+		},
+		{
+			name:               "Expiration is capped by the maximum lifetime",
+			idleTTL:            time.Hour,
+			maxLifetime:        90 * time.Minute,
+			age:                45 * time.Minute,
+			expectedFound:      true,
+			expectedExpiration: 45 * time.Minute,
+		},
+		{
+			name:               "No maximum lifetime",
+			idleTTL:            time.Minute,
+			age:                50 * time.Second,
+			expectedFound:      true,
+			expectedExpiration: time.Minute,
+		},
+		{
+			name:        "Idle for longer than the idle TTL",
+			idleTTL:     time.Minute,
+			maxLifetime: time.Hour,
+			age:         2 * time.Minute,
+		},
+		{
+			name:        "Maximum lifetime shorter than the idle TTL",
+			idleTTL:     time.Hour,
+			maxLifetime: time.Minute,
+			age:         2 * time.Minute,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Now())
+			c := NewTyped[string, string](zap.NewNop(), append(test.options, WithClock(clk))...)
+			c.SetWithSlidingExpiration("session", "alice", test.idleTTL, test.maxLifetime)
+			clk.Advance(test.age)
+
+			value, found := c.Get("session")
+			if found != test.expectedFound {
+				t.Fatalf("Expected found=%v, got (%v, %v)", test.expectedFound, value, found)
+			}
+			if !found {
+				return
+			}
+
+			if got := c.data["session"].Expiration.Sub(clk.Now()); got != test.expectedExpiration {
+				t.Errorf("Expected expiration in %v, got %v", test.expectedExpiration, got)
+			}
+			item, _ := c.expirations.peek()
+			if !item.expiration.Equal(c.data["session"].Expiration) {
+				t.Errorf("Expected the janitor to track expiration %v, got %v", c.data["session"].Expiration, item.expiration)
+			}
+		})
+	}
+}
+
+func TestSlidingExpirationOnlyExtendedByGet(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	c := NewTyped[string, string](zap.NewNop(), WithClock(clk))
+	c.SetWithSlidingExpiration("session", "alice", time.Minute, time.Hour)
+	clk.Advance(50 * time.Second)
+	expiration := c.data["session"].Expiration
+
+	c.GetOrLoad(t.Context(), "other", time.Minute, func(ctx context.Context) (string, error) { return "bob", nil })
+	c.purgeExpired()
+
+	if got := c.data["session"].Expiration; !got.Equal(expiration) {
+		t.Errorf("Expected expiration %v, got %v", expiration, got)
+	}
+}
+
+func TestSlidingExpirationSnapshot(t *testing.T) {
+	source := NewTyped[string, string](zap.NewNop())
+	source.SetWithSlidingExpiration("session", "alice", time.Minute, time.Hour)
+
+	var buf bytes.Buffer
+	if err := source.Snapshot(&buf); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	restored := NewTyped[string, string](zap.NewNop())
+	if err := restored.Restore(&buf); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	entry := restored.data["session"]
+	if entry.sliding != time.Minute || !entry.MaxExpiration.Equal(source.data["session"].MaxExpiration) {
+		t.Errorf("Sliding expiration was not restored: %+v", entry)
+	}
+}
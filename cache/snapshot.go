@@ -0,0 +1,211 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/crypto"
+)
+
+// snapshotVersion is written to every snapshot, so the format can evolve without misreading old files
+const snapshotVersion = 1
+
+// WithSnapshotEncryption encrypts snapshots written by Snapshot with AES-GCM using key,
+// and makes Restore decrypt them. The key is 16, 24 or 32 bytes long, e.g. read by crypto.ReadEncryptionKey.
+func WithSnapshotEncryption(key []byte) Option {
+	return func(o *options) {
+		o.snapshotKey = key
+	}
+}
+
+type snapshot struct {
+	Version int             `json:"version"`
+	Entries []snapshotEntry `json:"entries"`
+}
+
+type snapshotEntry struct {
+	Key            json.RawMessage `json:"key"`
+	Value          []byte          `json:"value"`
+	Expiration     time.Time       `json:"expiration"`
+	SoftExpiration time.Time       `json:"soft_expiration,omitzero"`
+	MaxExpiration  time.Time       `json:"max_expiration,omitzero"`
+	TTL            time.Duration   `json:"ttl,omitempty"`
+	SoftTTL        time.Duration   `json:"soft_ttl,omitempty"`
+	Sliding        time.Duration   `json:"sliding,omitempty"`
+	Tags           []string        `json:"tags,omitempty"`
+}
+
+// keyedEntry is a live cache entry copied out of the cache together with its key and tags
+type keyedEntry[K comparable, V any] struct {
+	key   K
+	entry Entry[V]
+	tags  []string
+}
+
+// SetCodec sets the codec used to encode values in snapshots. Default is JSONCodec for Typed and TypeCodec for Cache.
+func (c *Typed[K, V]) SetCodec(codec Codec[V]) {
+	c.lock.Lock()
+	defer c.unlock()
+	c.codec = codec
+}
+
+// Snapshot writes all entries which have not expired yet, with their expirations and tags, to w.
+// Values are encoded with the codec of the cache and the snapshot is encrypted if WithSnapshotEncryption is set.
+//
+// Example usage:
+//
+//	file, err := os.Create(snapshotPath)
+//	if err != nil {
+//		return err
+//	}
+//	defer file.Close()
+//	if err := c.Snapshot(file); err != nil {
+//		return err
+//	}
+func (c *Typed[K, V]) Snapshot(w io.Writer) error {
+	codec, key := c.snapshotSettings()
+	return writeSnapshot(w, c.liveEntries(c.clock.Now()), codec, key)
+}
+
+// Restore adds the entries of a snapshot written by Snapshot to the cache, skipping entries which expired in the meantime.
+// Restored entries replace existing entries with the same key. Nothing is restored if the snapshot cannot be read.
+func (c *Typed[K, V]) Restore(r io.Reader) error {
+	codec, key := c.snapshotSettings()
+
+	entries, err := readSnapshot[K](r, codec, key)
+	if err != nil {
+		return err
+	}
+	c.restore(entries, c.clock.Now())
+	return nil
+}
+
+// snapshotSettings returns the codec and the encryption key used for snapshots
+func (c *Typed[K, V]) snapshotSettings() (Codec[V], []byte) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.codec, c.snapshotKey
+}
+
+// liveEntries copies every entry which has not expired at now
+func (c *Typed[K, V]) liveEntries(now time.Time) []keyedEntry[K, V] {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	entries := make([]keyedEntry[K, V], 0, len(c.data))
+	for key, entry := range c.data {
+		if now.After(entry.Expiration) {
+			continue
+		}
+		entries = append(entries, keyedEntry[K, V]{
+			key:   key,
+			entry: entry,
+			tags:  append([]string(nil), c.keyTags[key]...),
+		})
+	}
+	return entries
+}
+
+// restore stores the entries which have not expired at now
+func (c *Typed[K, V]) restore(entries []keyedEntry[K, V], now time.Time) {
+	c.lock.Lock()
+	defer c.unlock()
+
+	for _, e := range entries {
+		if now.After(e.entry.Expiration) {
+			continue
+		}
+		c.store(e.key, e.entry)
+		c.tag(e.key, e.tags)
+	}
+}
+
+func writeSnapshot[K comparable, V any](w io.Writer, entries []keyedEntry[K, V], codec Codec[V], encryptionKey []byte) error {
+	s := snapshot{
+		Version: snapshotVersion,
+		Entries: make([]snapshotEntry, 0, len(entries)),
+	}
+	for _, e := range entries {
+		key, err := json.Marshal(e.key)
+		if err != nil {
+			return fmt.Errorf("failed to encode cache key %v: %s", e.key, err.Error())
+		}
+		value, err := codec.Encode(e.entry.Value)
+		if err != nil {
+			return fmt.Errorf("failed to encode value of cache key %v: %s", e.key, err.Error())
+		}
+		s.Entries = append(s.Entries, snapshotEntry{
+			Key:            key,
+			Value:          value,
+			Expiration:     e.entry.Expiration,
+			SoftExpiration: e.entry.SoftExpiration,
+			MaxExpiration:  e.entry.MaxExpiration,
+			TTL:            e.entry.ttl,
+			SoftTTL:        e.entry.softTTL,
+			Sliding:        e.entry.sliding,
+			Tags:           e.tags,
+		})
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache snapshot: %s", err.Error())
+	}
+	if encryptionKey != nil {
+		if data, err = crypto.Encrypt(encryptionKey, data); err != nil {
+			return fmt.Errorf("failed to encrypt cache snapshot: %s", err.Error())
+		}
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write cache snapshot: %s", err.Error())
+	}
+	return nil
+}
+
+func readSnapshot[K comparable, V any](r io.Reader, codec Codec[V], encryptionKey []byte) ([]keyedEntry[K, V], error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache snapshot: %s", err.Error())
+	}
+	if encryptionKey != nil {
+		if data, err = crypto.Decrypt(encryptionKey, data); err != nil {
+			return nil, fmt.Errorf("failed to decrypt cache snapshot: %s", err.Error())
+		}
+	}
+
+	var s snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to decode cache snapshot: %s", err.Error())
+	}
+	if s.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported cache snapshot version %d", s.Version)
+	}
+
+	entries := make([]keyedEntry[K, V], 0, len(s.Entries))
+	for _, e := range s.Entries {
+		var key K
+		if err := json.Unmarshal(e.Key, &key); err != nil {
+			return nil, fmt.Errorf("failed to decode cache key %s: %s", e.Key, err.Error())
+		}
+		value, err := codec.Decode(e.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode value of cache key %s: %s", e.Key, err.Error())
+		}
+		entries = append(entries, keyedEntry[K, V]{
+			key: key,
+			entry: Entry[V]{
+				Value:          value,
+				Expiration:     e.Expiration,
+				SoftExpiration: e.SoftExpiration,
+				MaxExpiration:  e.MaxExpiration,
+				ttl:            e.TTL,
+				softTTL:        e.SoftTTL,
+				sliding:        e.Sliding,
+			},
+			tags: e.Tags,
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestTypedSnapshotRoundTrip(t *testing.T) {
+	source := NewTyped[string, []price](zap.NewNop())
+	expiration := time.Now().Add(time.Hour).Truncate(time.Second)
+	source.SetExpiredAtTime("today", []price{{Hour: 1, Value: 2.5}}, expiration)
+	source.SetWithSoftTTL("tomorrow", []price{{Hour: 2, Value: 3.5}}, time.Minute, time.Hour)
+	source.SetWithTags("tagged", []price{{Hour: 3, Value: 4.5}}, time.Hour, "date:today")
+	source.SetExpiredAfterTimePeriod("expired", []price{{Hour: 4}}, -time.Minute)
+
+	var buf bytes.Buffer
+	if err := source.Snapshot(&buf); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+
+	restored := NewTyped[string, []price](zap.NewNop())
+	if err := restored.Restore(&buf); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	if value, found := restored.Get("today"); !found || !reflect.DeepEqual(value, []price{{Hour: 1, Value: 2.5}}) {
+		t.Errorf("Expected restored value, got (%v, %v)", value, found)
+	}
+	if !restored.data["today"].Expiration.Equal(expiration) {
+		t.Errorf("Expected expiration %v, got %v", expiration, restored.data["today"].Expiration)
+	}
+	if entry := restored.data["tomorrow"]; entry.softTTL != time.Minute || entry.ttl != time.Hour || entry.SoftExpiration.IsZero() {
+		t.Errorf("Soft expiration was not restored: %+v", entry)
+	}
+	if _, exists := restored.data["expired"]; exists {
+		t.Error("Expired entries should not be part of the snapshot")
+	}
+
+	restored.InvalidateTag("date:today")
+	if _, found := restored.Get("tagged"); found {
+		t.Error("Tags should survive the round trip")
+	}
+}
+
+func TestCacheSnapshotKeepsRegisteredTypes(t *testing.T) {
+	source := NewCache(zap.NewNop())
+	source.RegisterType("prices", []price{})
+	source.SetExpiredAfterTimePeriod("prices", []price{{Hour: 1, Value: 2.5}}, time.Hour)
+	source.SetExpiredAfterTimePeriod("count", 42, time.Hour)
+	source.SetExpiredAfterTimePeriod("name", "alice", time.Hour)
+
+	var buf bytes.Buffer
+	if err := source.Snapshot(&buf); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+
+	restored := NewCache(zap.NewNop())
+	restored.RegisterType("prices", []price{})
+	if err := restored.Restore(&buf); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	tests := []struct {
+		key      string
+		expected interface{}
+	}{
+		{key: "prices", expected: []price{{Hour: 1, Value: 2.5}}},
+		{key: "count", expected: 42},
+		{key: "name", expected: "alice"},
+	}
+	for _, test := range tests {
+		value, found := restored.Get(test.key)
+		if !found || !reflect.DeepEqual(value, test.expected) {
+			t.Errorf("Key %q: expected %#v, got (%#v, %v)", test.key, test.expected, value, found)
+		}
+	}
+}
+
+func TestSnapshotUnregisteredType(t *testing.T) {
+	c := NewCache(zap.NewNop())
+	c.SetExpiredAfterTimePeriod("prices", []price{{Hour: 1}}, time.Hour)
+
+	var buf bytes.Buffer
+	if err := c.Snapshot(&buf); err == nil || !strings.Contains(err.Error(), "not registered") {
+		t.Errorf("Expected an error about the unregistered type, got %v", err)
+	}
+}
+
+func TestSnapshotEncryption(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	source := NewCache(zap.NewNop(), WithSnapshotEncryption(key))
+	source.SetExpiredAfterTimePeriod("secret", "session-token", time.Hour)
+
+	var buf bytes.Buffer
+	if err := source.Snapshot(&buf); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("session-token")) {
+		t.Error("Encrypted snapshot should not contain plain values")
+	}
+	encrypted := buf.Bytes()
+
+	tests := []struct {
+		name        string
+		options     []Option
+		expectError bool
+	}{
+		{name: "Restore with the same key", options: []Option{WithSnapshotEncryption(key)}},
+		{name: "Restore with another key", options: []Option{WithSnapshotEncryption([]byte("fedcba9876543210fedcba9876543210"))}, expectError: true},
+		{name: "Restore without key", expectError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			restored := NewCache(zap.NewNop(), test.options...)
+			err := restored.Restore(bytes.NewReader(encrypted))
+			if (err != nil) != test.expectError {
+				t.Fatalf("Expected error=%v, got %v", test.expectError, err)
+			}
+			if test.expectError {
+				if restored.Len() != 0 {
+					t.Error("Nothing should be restored from an unreadable snapshot")
+				}
+				return
+			}
+			if value, found := restored.Get("secret"); !found || value != "session-token" {
+				t.Errorf("Expected (session-token, true), got (%v, %v)", value, found)
+			}
+		})
+	}
+}
+
+func TestRestoreDoesNotTagRefusedValue(t *testing.T) {
+	source := NewTyped[string, string](zap.NewNop())
+	source.SetWithTags("small", "value", time.Hour, "date:today")
+	source.SetWithTags("large", "larger than ten bytes", time.Hour, "date:today")
+
+	var buf bytes.Buffer
+	if err := source.Snapshot(&buf); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	restored := NewTyped[string, string](zap.NewNop(), WithMaxBytes(10))
+	if err := restored.Restore(&buf); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	if _, found := restored.Get("large"); found {
+		t.Fatal("Value larger than the byte limit should not be restored")
+	}
+	if _, tagged := restored.keyTags["large"]; tagged || len(restored.tags["date:today"]) != 1 {
+		t.Errorf("Expected only the restored value to be tagged, got %v", restored.tags)
+	}
+}
+
+func TestShardedSnapshotRoundTrip(t *testing.T) {
+	source := NewShardedCache(zap.NewNop(), 4)
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		source.SetExpiredAfterTimePeriod(key, key, time.Hour)
+	}
+
+	var buf bytes.Buffer
+	if err := source.Snapshot(&buf); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+
+	// restoring into a cache with another number of shards redistributes the keys
+	restored := NewShardedCache(zap.NewNop(), 3)
+	if err := restored.Restore(&buf); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		if value, found := restored.Get(key); !found || value != key {
+			t.Errorf("Key %q: expected (%s, true), got (%v, %v)", key, key, value, found)
+		}
+	}
+}
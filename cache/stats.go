@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Stats is a point-in-time view of the cache counters.
+// Hits and Misses count lookups through Get and GetOrLoad.
+// Expirations count entries removed because they expired and Evictions count entries removed to make room in a full cache.
+// Size is the number of entries currently stored, including expired entries which have not been purged yet.
+// Bytes is the total size of the stored values, only measured when the cache is bounded by WithMaxBytes.
+type Stats struct {
+	Hits        uint64
+	Misses      uint64
+	Expirations uint64
+	Evictions   uint64
+	Size        int
+	Bytes       int64
+}
+
+// StatsProvider is implemented by every cache in this package, so their statistics can be exported by NewCollector
+type StatsProvider interface {
+	Stats() Stats
+}
+
+// counters are updated atomically, so reading the statistics never blocks the cache
+type counters struct {
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	expirations atomic.Uint64
+	evictions   atomic.Uint64
+}
+
+// Stats returns the current statistics of the cache
+func (c *Typed[K, V]) Stats() Stats {
+	c.lock.RLock()
+	size, bytes := len(c.data), c.bytes
+	c.lock.RUnlock()
+
+	return Stats{
+		Hits:        c.stats.hits.Load(),
+		Misses:      c.stats.misses.Load(),
+		Expirations: c.stats.expirations.Load(),
+		Evictions:   c.stats.evictions.Load(),
+		Size:        size,
+		Bytes:       bytes,
+	}
+}
+
+// countRemoval records the removal of an existing entry in the counters
+func (c *counters) countRemoval(reason EvictionReason) {
+	switch reason {
+	case ReasonExpired:
+		c.expirations.Add(1)
+	case ReasonCapacity:
+		c.evictions.Add(1)
+	}
+}
+
+// tierStatsProvider is implemented by Tiered, whose hits are also exported per tier
+type tierStatsProvider interface {
+	TierStats() TieredStats
+}
+
+// collector exports the statistics of a cache as Prometheus metrics
+type collector struct {
+	cache       StatsProvider
+	tiers       tierStatsProvider // nil unless the cache is tiered
+	tierHits    *prometheus.Desc
+	hits        *prometheus.Desc
+	misses      *prometheus.Desc
+	expirations *prometheus.Desc
+	evictions   *prometheus.Desc
+	size        *prometheus.Desc
+	bytes       *prometheus.Desc
+}
+
+// NewCollector returns a prometheus.Collector exporting the statistics of cache, labelled with cache="<name>".
+// Use a different name for every cache registered in the same registry.
+// For a Tiered cache, the hits of each tier are also exported, labelled with tier="l1" or tier="l2".
+//
+// Example usage:
+//
+//	prices := cache.NewTyped[string, []Price](logger)
+//	handler := monitoring.PrometheusHandler(cache.NewCollector("prices", prices))
+func NewCollector(name string, cache StatsProvider) prometheus.Collector {
+	labels := prometheus.Labels{"cache": name}
+	tiers, _ := cache.(tierStatsProvider)
+	return &collector{
+		cache:       cache,
+		tiers:       tiers,
+		tierHits:    prometheus.NewDesc("cache_tier_hits_total", "Total number of cache lookups which found a value, by tier", []string{"tier"}, labels),
+		hits:        prometheus.NewDesc("cache_hits_total", "Total number of cache lookups which found a value", nil, labels),
+		misses:      prometheus.NewDesc("cache_misses_total", "Total number of cache lookups which did not find a value", nil, labels),
+		expirations: prometheus.NewDesc("cache_expirations_total", "Total number of cache entries removed because they expired", nil, labels),
+		evictions:   prometheus.NewDesc("cache_evictions_total", "Total number of cache entries evicted to make room in a full cache", nil, labels),
+		size:        prometheus.NewDesc("cache_entries", "Number of entries currently stored in the cache", nil, labels),
+		bytes:       prometheus.NewDesc("cache_bytes", "Total size of the values stored in the cache, when it is bounded by bytes", nil, labels),
+	}
+}
+
+// Describe sends the descriptors of all cache metrics
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.expirations
+	ch <- c.evictions
+	ch <- c.size
+	ch <- c.bytes
+	if c.tiers != nil {
+		ch <- c.tierHits
+	}
+}
+
+// Collect reads the statistics of the cache and sends them as metrics
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.cache.Stats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.expirations, prometheus.CounterValue, float64(stats.Expirations))
+	ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(stats.Evictions))
+	ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(stats.Size))
+	ch <- prometheus.MustNewConstMetric(c.bytes, prometheus.GaugeValue, float64(stats.Bytes))
+	if c.tiers != nil {
+		tiers := c.tiers.TierStats()
+		ch <- prometheus.MustNewConstMetric(c.tierHits, prometheus.CounterValue, float64(tiers.L1Hits), "l1")
+		ch <- prometheus.MustNewConstMetric(c.tierHits, prometheus.CounterValue, float64(tiers.L2Hits), "l2")
+	}
+}
@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+)
+
+func TestStats(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []Option
+		act      func(c *Cache)
+		expected Stats
+	}{
+		{
+			name: "Hits and misses",
+			act: func(c *Cache) {
+				c.SetExpiredAfterTimePeriod("key", "value", time.Minute)
+				c.Get("key")
+				c.Get("key")
+				c.Get("missing")
+			},
+			expected: Stats{Hits: 2, Misses: 1, Size: 1},
+		},
+		{
+			name:    "Hits in a bounded cache",
+			options: []Option{WithMaxEntries(10)},
+			act: func(c *Cache) {
+				c.SetExpiredAfterTimePeriod("key", "value", time.Minute)
+				c.Get("key")
+			},
+			expected: Stats{Hits: 1, Size: 1},
+		},
+		{
+			name: "Expired on Get is a miss",
+			act: func(c *Cache) {
+				c.SetExpiredAfterTimePeriod("key", "value", -time.Second)
+				c.Get("key")
+			},
+			expected: Stats{Misses: 1, Expirations: 1},
+		},
+		{
+			name: "Expired by janitor",
+			act: func(c *Cache) {
+				c.SetExpiredAfterTimePeriod("first", "value", -time.Second)
+				c.SetExpiredAfterTimePeriod("second", "value", -time.Second)
+				c.purgeExpired()
+			},
+			expected: Stats{Expirations: 2},
+		},
+		{
+			name:    "Evicted for capacity",
+			options: []Option{WithMaxEntries(2)},
+			act: func(c *Cache) {
+				c.SetExpiredAfterTimePeriod("a", 1, time.Minute)
+				c.SetExpiredAfterTimePeriod("b", 2, time.Minute)
+				c.SetExpiredAfterTimePeriod("c", 3, time.Minute)
+			},
+			expected: Stats{Evictions: 1, Size: 2},
+		},
+		{
+			name: "Deletes are not counted",
+			act: func(c *Cache) {
+				c.SetExpiredAfterTimePeriod("user:1", "alice", time.Minute)
+				c.SetExpiredAfterTimePeriod("user:2", "bob", time.Minute)
+				c.Delete("user:1")
+				c.DeletePrefix("user:")
+			},
+			expected: Stats{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := NewCache(zap.NewNop(), test.options...)
+			test.act(c)
+			if stats := c.Stats(); stats != test.expected {
+				t.Errorf("Expected %+v, got %+v", test.expected, stats)
+			}
+		})
+	}
+}
+
+func TestShardedStats(t *testing.T) {
+	c := NewShardedCache(zap.NewNop(), 4)
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		c.SetExpiredAfterTimePeriod(key, key, time.Minute)
+		c.Get(key)
+	}
+	c.Get("missing")
+
+	expected := Stats{Hits: 5, Misses: 1, Size: 5}
+	if stats := c.Stats(); stats != expected {
+		t.Errorf("Expected %+v, got %+v", expected, stats)
+	}
+}
+
+func TestCollector(t *testing.T) {
+	c := NewCache(zap.NewNop(), WithMaxEntries(1))
+	c.SetExpiredAfterTimePeriod("old", 1, time.Minute)
+	c.SetExpiredAfterTimePeriod("new", 2, time.Minute)
+	c.Get("new")
+	c.Get("old")
+
+	expected := `
+# HELP cache_bytes Total size of the values stored in the cache, when it is bounded by bytes
+# TYPE cache_bytes gauge
+cache_bytes{cache="prices"} 0
+# HELP cache_entries Number of entries currently stored in the cache
+# TYPE cache_entries gauge
+cache_entries{cache="prices"} 1
+# HELP cache_evictions_total Total number of cache entries evicted to make room in a full cache
+# TYPE cache_evictions_total counter
+cache_evictions_total{cache="prices"} 1
+# HELP cache_expirations_total Total number of cache entries removed because they expired
+# TYPE cache_expirations_total counter
+cache_expirations_total{cache="prices"} 0
+# HELP cache_hits_total Total number of cache lookups which found a value
+# TYPE cache_hits_total counter
+cache_hits_total{cache="prices"} 1
+# HELP cache_misses_total Total number of cache lookups which did not find a value
+# TYPE cache_misses_total counter
+cache_misses_total{cache="prices"} 1
+`
+	if err := testutil.CollectAndCompare(NewCollector("prices", c), strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
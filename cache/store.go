@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Store is the interface shared by the in-memory caches and the shared RedisStore,
+// so services can switch between local and shared caching by configuration.
+type Store interface {
+	// SetExpiredAfterTimePeriod caches the value for the given duration
+	SetExpiredAfterTimePeriod(key string, value interface{}, duration time.Duration)
+	// SetExpiredAtTime caches the value until the given time
+	SetExpiredAtTime(key string, value interface{}, expiredTime time.Time)
+	// Get returns the cached value and `true`, or `nil` and `false` if it is missing or expired
+	Get(key string) (interface{}, bool)
+	// Delete removes the value of key. It is a no-op if the key is not cached
+	Delete(key string)
+	// DeletePrefix removes every value whose key starts with prefix
+	DeletePrefix(prefix string)
+	// RegisterType registers the type of sample under name, so values of that type keep it when they leave the process
+	RegisterType(name string, sample interface{})
+	// Close releases the resources of the store
+	Close()
+}
+
+// TTLStore is a Store which can tell how long it still keeps a value.
+// Tiered uses it to never keep a value copied from L2 in L1 for longer than L2 does.
+type TTLStore interface {
+	Store
+	// TTL returns how long the value of key stays in the store, and `false` if it is missing, expired or its TTL is unknown
+	TTL(key string) (time.Duration, bool)
+}
+
+var (
+	_ Store = (*Cache)(nil)
+	_ Store = (*ShardedCache)(nil)
+	_ Store = (*RedisStore)(nil)
+	_ Store = (*ReplicatedCache)(nil)
+	_ Store = (*Tiered)(nil)
+
+	_ TTLStore = (*Cache)(nil)
+	_ TTLStore = (*ShardedCache)(nil)
+	_ TTLStore = (*RedisStore)(nil)
+	_ TTLStore = (*ReplicatedCache)(nil)
+	_ TTLStore = (*Tiered)(nil)
+)
+
+// Backend names accepted by StoreConfig
+const (
+	BackendMemory = "memory" // BackendMemory keeps values in a private in-memory Cache
+	BackendRedis  = "redis"  // BackendRedis keeps values in a Redis server shared by all replicas
+)
+
+// StoreConfig selects and configures the backend returned by NewStore
+type StoreConfig struct {
+	Backend string      // Backend is BackendMemory or BackendRedis. Empty means BackendMemory
+	Redis   RedisConfig // Redis configures the connection when Backend is BackendRedis
+}
+
+// NewStore returns the Store selected by config. Options only apply to the in-memory backend.
+//
+// Example usage:
+//
+//	store, err := cache.NewStore(logger, cache.StoreConfig{
+//		Backend: os.Getenv("CACHE_BACKEND"),
+//		Redis:   cache.RedisConfig{Addr: os.Getenv("REDIS_ADDR")},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer store.Close()
+func NewStore(logger *zap.Logger, config StoreConfig, opts ...Option) (Store, error) {
+	switch config.Backend {
+	case "", BackendMemory:
+		return NewCache(logger, opts...), nil
+	case BackendRedis:
+		return NewRedisStore(logger, config.Redis)
+	default:
+		return nil, fmt.Errorf("unknown cache backend %q", config.Backend)
+	}
+}
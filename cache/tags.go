@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"time"
+)
+
+// SetWithTags adds new key-value pair to the cache which expires after ttl and belongs to the given tags.
+// All entries sharing a tag can then be removed at once with InvalidateTag.
+// Setting the key again, with or without tags, replaces its previous tags.
+//
+// Example usage:
+//
+//	c.SetWithTags("prices:2024-01-01:user-1", prices, time.Hour, "user:user-1", "date:2024-01-01")
+//	// user-1 changed their settings
+//	c.InvalidateTag("user:user-1")
+func (c *Typed[K, V]) SetWithTags(key K, value V, ttl time.Duration, tags ...string) {
+	c.lock.Lock()
+	defer c.unlock()
+
+	c.store(key, Entry[V]{
+		Value:      value,
+		Expiration: c.clock.Now().Add(ttl),
+		ttl:        ttl,
+	})
+	c.tag(key, tags)
+}
+
+// InvalidateTag removes all cache entries which were set with the given tag.
+// It only visits the matching entries, which are reported to eviction callbacks with ReasonDeleteAll.
+func (c *Typed[K, V]) InvalidateTag(tag string) {
+	c.lock.Lock()
+	defer c.unlock()
+
+	for key := range c.tags[tag] {
+		c.remove(key, ReasonDeleteAll)
+	}
+}
+
+// DeletePrefix removes all cache entries whose key starts with prefix.
+// Keys are kept in a sorted index, so only the matching entries are visited.
+// It is only supported when K is string; for other key types it is a no-op.
+// Removed entries are reported to eviction callbacks with ReasonDeleteAll.
+//
+// Entries added by writing to Cache.Data directly are not indexed and are not removed by DeletePrefix.
+func (c *Typed[K, V]) DeletePrefix(prefix string) {
+	if c.prefixes == nil {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.unlock()
+
+	for _, key := range c.prefixes.keysWithPrefix(prefix) {
+		c.remove(any(key).(K), ReasonDeleteAll)
+	}
+}
+
+// tag adds the key to the given tags. The caller must hold the lock.
+// It is a no-op if the key is not in the cache, e.g. because store refused a value larger than the byte limit.
+func (c *Typed[K, V]) tag(key K, tags []string) {
+	if _, stored := c.data[key]; !stored {
+		return
+	}
+	for _, tag := range tags {
+		keys, ok := c.tags[tag]
+		if !ok {
+			keys = make(map[K]struct{})
+			c.tags[tag] = keys
+		}
+		if _, tagged := keys[key]; tagged {
+			continue
+		}
+		keys[key] = struct{}{}
+		c.keyTags[key] = append(c.keyTags[key], tag)
+	}
+}
+
+// untag removes the key from all of its tags. The caller must hold the lock.
+func (c *Typed[K, V]) untag(key K) {
+	for _, tag := range c.keyTags[key] {
+		keys := c.tags[tag]
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+	delete(c.keyTags, key)
+}
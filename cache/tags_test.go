@@ -0,0 +1,188 @@
+package cache
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestDeletePrefix(t *testing.T) {
+	tests := []struct {
+		name              string
+		keysToAdd         []string
+		prefix            string
+		expectedRemaining []string
+	}{
+		{
+			name:              "Delete keys with prefix",
+			keysToAdd:         []string{"user:1", "user:2", "post:1"},
+			prefix:            "user:",
+			expectedRemaining: []string{"post:1"},
+		},
+		{
+			name:              "Keep keys containing the prefix in the middle",
+			keysToAdd:         []string{"user:1", "post:user:1", "admin-user:1"},
+			prefix:            "user:",
+			expectedRemaining: []string{"post:user:1", "admin-user:1"},
+		},
+		{
+			name:              "Delete with no matches",
+			keysToAdd:         []string{"key1", "key2"},
+			prefix:            "nonexistent",
+			expectedRemaining: []string{"key1", "key2"},
+		},
+		{
+			name:              "Delete with empty prefix",
+			keysToAdd:         []string{"key1", "key2"},
+			prefix:            "",
+			expectedRemaining: []string{},
+		},
+		{
+			name:              "Delete from empty cache",
+			keysToAdd:         []string{},
+			prefix:            "any",
+			expectedRemaining: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, _ := setupTestCache(t)
+			for _, key := range test.keysToAdd {
+				c.SetExpiredAfterTimePeriod(key, "value", time.Hour)
+			}
+
+			c.DeletePrefix(test.prefix)
+
+			if c.Len() != len(test.expectedRemaining) {
+				t.Errorf("Expected %d keys remaining, got %d", len(test.expectedRemaining), c.Len())
+			}
+			for _, key := range test.expectedRemaining {
+				if _, exists := c.data[key]; !exists {
+					t.Errorf("Key %q should still exist", key)
+				}
+			}
+		})
+	}
+}
+
+func TestDeletePrefixNonStringKey(t *testing.T) {
+	c := NewTyped[int, string](zap.NewNop())
+	c.SetExpiredAfterTimePeriod(1, "one", time.Hour)
+
+	c.DeletePrefix("1")
+	if _, exists := c.Get(1); !exists {
+		t.Error("DeletePrefix should be a no-op for non-string keys")
+	}
+}
+
+func TestPrefixIndex(t *testing.T) {
+	index := newPrefixIndex()
+	keys := make([]string, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		keys = append(keys, fmt.Sprintf("user:%04d", i))
+	}
+	for _, i := range []int{500, 3, 999, 0} {
+		index.insert(keys[i])
+	}
+	for _, key := range keys {
+		index.insert(key)
+	}
+	index.insert(keys[0]) // inserting twice is a no-op
+
+	if got := index.keysWithPrefix("user:"); !slices.Equal(got, keys) {
+		t.Errorf("Expected all %d keys in order, got %d keys", len(keys), len(got))
+	}
+	if got := index.keysWithPrefix("user:001"); !slices.Equal(got, keys[10:20]) {
+		t.Errorf("Expected %v, got %v", keys[10:20], got)
+	}
+
+	for _, key := range keys[:500] {
+		index.delete(key)
+	}
+	index.delete("nonexistent")
+	if got := index.keysWithPrefix(""); !slices.Equal(got, keys[500:]) {
+		t.Errorf("Expected %d remaining keys, got %d", 500, len(got))
+	}
+}
+
+func TestInvalidateTag(t *testing.T) {
+	c := NewCache(zap.NewNop())
+	c.SetWithTags("prices:user-1:today", 1, time.Hour, "user:user-1", "date:today")
+	c.SetWithTags("prices:user-1:tomorrow", 2, time.Hour, "user:user-1", "date:tomorrow")
+	c.SetWithTags("prices:user-2:today", 3, time.Hour, "user:user-2", "date:today")
+	c.SetExpiredAfterTimePeriod("untagged", 4, time.Hour)
+
+	c.InvalidateTag("user:user-1")
+	assertKeys(t, c.Typed, []string{"prices:user-2:today", "untagged"}, []string{"prices:user-1:today", "prices:user-1:tomorrow"})
+
+	c.InvalidateTag("date:today")
+	assertKeys(t, c.Typed, []string{"untagged"}, []string{"prices:user-2:today"})
+
+	c.InvalidateTag("nonexistent")
+	if len(c.tags) != 0 || len(c.keyTags) != 0 {
+		t.Errorf("Tag index should be empty, got %v and %v", c.tags, c.keyTags)
+	}
+}
+
+func TestTagsAreDroppedWithEntry(t *testing.T) {
+	tests := []struct {
+		name   string
+		remove func(c *Cache)
+	}{
+		{name: "Overwrite without tags", remove: func(c *Cache) { c.SetExpiredAfterTimePeriod("key", 2, time.Hour) }},
+		{name: "Delete", remove: func(c *Cache) { c.Delete("key") }},
+		{name: "DeletePrefix", remove: func(c *Cache) { c.DeletePrefix("k") }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := NewCache(zap.NewNop())
+			c.SetWithTags("key", 1, time.Hour, "tag")
+
+			test.remove(c)
+			c.SetExpiredAfterTimePeriod("key", 3, time.Hour)
+
+			c.InvalidateTag("tag")
+			if value, exists := c.Get("key"); !exists || value != 3 {
+				t.Errorf("Re-added key should not belong to the old tag, got (%v, %v)", value, exists)
+			}
+		})
+	}
+}
+
+func TestTagsAreNotKeptForRefusedValue(t *testing.T) {
+	c := NewTyped[string, string](zap.NewNop(), WithMaxBytes(10))
+	c.SetWithTags("key", "larger than ten bytes", time.Hour, "user:1")
+
+	if _, found := c.Get("key"); found {
+		t.Fatal("Value larger than the byte limit should not be cached")
+	}
+	if len(c.tags) != 0 || len(c.keyTags) != 0 {
+		t.Errorf("Expected no tags for a refused value, got %v and %v", c.tags, c.keyTags)
+	}
+}
+
+func TestShardedDeletePrefixAndTags(t *testing.T) {
+	c := NewShardedCache(zap.NewNop(), 4)
+	for i := 0; i < 100; i++ {
+		c.SetWithTags(fmt.Sprintf("user:%d", i), i, time.Hour, fmt.Sprintf("group:%d", i%2))
+		c.SetExpiredAfterTimePeriod(fmt.Sprintf("post:%d", i), i, time.Hour)
+	}
+
+	c.InvalidateTag("group:0")
+	c.DeletePrefix("post:")
+
+	for i := 0; i < 100; i++ {
+		_, userExists := c.Get(fmt.Sprintf("user:%d", i))
+		if userExists != (i%2 == 1) {
+			t.Errorf("Key user:%d: expected exists=%v, got %v", i, i%2 == 1, userExists)
+		}
+		if _, postExists := c.Get(fmt.Sprintf("post:%d", i)); postExists {
+			t.Errorf("Key post:%d should have been deleted", i)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Tiered is a two-tier Store: a small in-process Cache (L1) in front of a larger, usually shared, Store (L2).
+// Get checks L1 first and then L2, copying values found in L2 into L1. Writes and deletes go through both tiers.
+//
+// L1 keeps values for at most its own TTL, so a value changed in L2 by another replica is seen after that TTL at the latest.
+// A value copied from L2 is never kept in L1 for longer than L2 keeps it, so an expiration set with SetExpiredAtTime holds in both tiers.
+// Combine it with ReplicatedCache as L1 to drop such values sooner.
+type Tiered struct {
+	l1     *Cache
+	l2     Store
+	l1TTL  time.Duration
+	l1Hits atomic.Uint64
+	l2Hits atomic.Uint64
+	misses atomic.Uint64
+}
+
+// TieredStats are the statistics of a Tiered cache. Hits are counted by the tier which served them.
+type TieredStats struct {
+	L1Hits uint64
+	L2Hits uint64
+	Misses uint64
+}
+
+// NewTiered returns a Tiered cache keeping values in l1 for at most l1TTL and in l2 for the TTL given when they are set.
+//
+// Example usage:
+//
+//	shared, err := cache.NewRedisStore(logger, cache.RedisConfig{Addr: redisAddr})
+//	if err != nil {
+//		return err
+//	}
+//	prices := cache.NewTiered(cache.NewCache(logger, cache.WithMaxEntries(1000)), shared, time.Minute)
+//	prices.SetExpiredAfterTimePeriod(today, todayPrices, time.Hour) // one minute in L1, one hour in L2
+func NewTiered(l1 *Cache, l2 Store, l1TTL time.Duration) *Tiered {
+	return &Tiered{l1: l1, l2: l2, l1TTL: l1TTL}
+}
+
+// SetExpiredAfterTimePeriod caches the value in L2 for duration and in L1 for at most the L1 TTL
+func (t *Tiered) SetExpiredAfterTimePeriod(key string, value interface{}, duration time.Duration) {
+	t.SetWithTTLs(key, value, min(duration, t.l1TTL), duration)
+}
+
+// SetExpiredAtTime caches the value in L2 until expiredTime and in L1 until expiredTime or the end of the L1 TTL, whichever comes first
+func (t *Tiered) SetExpiredAtTime(key string, value interface{}, expiredTime time.Time) {
+	l1Expiration := t.l1.clock.Now().Add(t.l1TTL)
+	if expiredTime.Before(l1Expiration) {
+		l1Expiration = expiredTime
+	}
+	t.l2.SetExpiredAtTime(key, value, expiredTime)
+	t.l1.SetExpiredAtTime(key, value, l1Expiration)
+}
+
+// SetWithTTLs caches the value in L1 for l1TTL and in L2 for l2TTL, regardless of the L1 TTL of the cache
+func (t *Tiered) SetWithTTLs(key string, value interface{}, l1TTL time.Duration, l2TTL time.Duration) {
+	t.l2.SetExpiredAfterTimePeriod(key, value, l2TTL)
+	t.l1.SetExpiredAfterTimePeriod(key, value, l1TTL)
+}
+
+// Get returns the value from L1, or from L2 in which case it is also cached in L1 for the L1 TTL,
+// or for the time L2 still keeps it if that is shorter. The value is not copied to L1 if L2 does not implement TTLStore
+// or cannot tell how long it keeps the value.
+// If the value is in neither tier, it returns `nil` and `false`.
+func (t *Tiered) Get(key string) (interface{}, bool) {
+	if value, found := t.l1.Get(key); found {
+		t.l1Hits.Add(1)
+		return value, true
+	}
+	value, found := t.l2.Get(key)
+	if !found {
+		t.misses.Add(1)
+		return nil, false
+	}
+	t.l2Hits.Add(1)
+	if l2, ok := t.l2.(TTLStore); ok {
+		if remaining, known := l2.TTL(key); known {
+			t.l1.SetExpiredAfterTimePeriod(key, value, min(remaining, t.l1TTL))
+		}
+	}
+	return value, true
+}
+
+// TTL returns how long the value of key stays in the tiered cache, which is how long L2 keeps it.
+// It falls back to L1 if L2 does not implement TTLStore.
+func (t *Tiered) TTL(key string) (time.Duration, bool) {
+	if l2, ok := t.l2.(TTLStore); ok {
+		return l2.TTL(key)
+	}
+	return t.l1.TTL(key)
+}
+
+// Delete removes the value of key from both tiers
+func (t *Tiered) Delete(key string) {
+	t.l2.Delete(key)
+	t.l1.Delete(key)
+}
+
+// DeletePrefix removes every value whose key starts with prefix from both tiers
+func (t *Tiered) DeletePrefix(prefix string) {
+	t.l2.DeletePrefix(prefix)
+	t.l1.DeletePrefix(prefix)
+}
+
+// RegisterType registers the type of sample in both tiers
+func (t *Tiered) RegisterType(name string, sample interface{}) {
+	t.l1.RegisterType(name, sample)
+	t.l2.RegisterType(name, sample)
+}
+
+// Close closes both tiers
+func (t *Tiered) Close() {
+	t.l1.Close()
+	t.l2.Close()
+}
+
+// Stats returns the statistics of the tiered cache as a whole: Hits include the hits of both tiers,
+// while Size, Expirations and Evictions are those of L1. Use TierStats to tell the tiers apart.
+func (t *Tiered) Stats() Stats {
+	stats := t.l1.Stats()
+	tiers := t.TierStats()
+	stats.Hits = tiers.L1Hits + tiers.L2Hits
+	stats.Misses = tiers.Misses
+	return stats
+}
+
+// TierStats returns how many lookups were served by each tier
+func (t *Tiered) TierStats() TieredStats {
+	return TieredStats{
+		L1Hits: t.l1Hits.Load(),
+		L2Hits: t.l2Hits.Load(),
+		Misses: t.misses.Load(),
+	}
+}
@@ -0,0 +1,198 @@
+package cache
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/clock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+)
+
+func newTestTiered(l1TTL time.Duration) (*Tiered, *Cache, *Cache) {
+	clk := clock.NewFake(time.Now())
+	l1 := NewCache(zap.NewNop(), WithClock(clk))
+	l2 := NewCache(zap.NewNop(), WithClock(clk))
+	return NewTiered(l1, l2, l1TTL), l1, l2
+}
+
+func TestTieredGet(t *testing.T) {
+	c, l1, l2 := newTestTiered(time.Minute)
+	l1.SetExpiredAfterTimePeriod("local", "l1 value", time.Minute)
+	l2.SetExpiredAfterTimePeriod("local", "l2 value", time.Minute)
+	l2.SetExpiredAfterTimePeriod("shared", "l2 value", time.Hour)
+
+	if value, found := c.Get("local"); !found || value != "l1 value" {
+		t.Errorf("Expected L1 to be checked first, got (%v, %v)", value, found)
+	}
+	if value, found := c.Get("shared"); !found || value != "l2 value" {
+		t.Errorf("Expected the value of L2, got (%v, %v)", value, found)
+	}
+	if value, found := l1.Get("shared"); !found || value != "l2 value" {
+		t.Errorf("A value found in L2 should be copied to L1, got (%v, %v)", value, found)
+	}
+	if _, found := c.Get("missing"); found {
+		t.Error("Missing key should not be found")
+	}
+
+	expected := TieredStats{L1Hits: 1, L2Hits: 1, Misses: 1}
+	if stats := c.TierStats(); stats != expected {
+		t.Errorf("Expected %+v, got %+v", expected, stats)
+	}
+	if stats := c.Stats(); stats.Hits != 2 || stats.Misses != 1 || stats.Size != 2 {
+		t.Errorf("Expected 2 hits, 1 miss and 2 entries, got %+v", stats)
+	}
+}
+
+func TestTieredSet(t *testing.T) {
+	tests := []struct {
+		name       string
+		set        func(c *Tiered, now time.Time)
+		expectedL1 time.Duration
+		expectedL2 time.Duration
+	}{
+		{
+			name:       "Duration longer than the L1 TTL",
+			set:        func(c *Tiered, now time.Time) { c.SetExpiredAfterTimePeriod("key", "value", time.Hour) },
+			expectedL1: time.Minute,
+			expectedL2: time.Hour,
+		},
+		{
+			name:       "Duration shorter than the L1 TTL",
+			set:        func(c *Tiered, now time.Time) { c.SetExpiredAfterTimePeriod("key", "value", time.Second) },
+			expectedL1: time.Second,
+			expectedL2: time.Second,
+		},
+		{
+			name:       "Time after the L1 TTL",
+			set:        func(c *Tiered, now time.Time) { c.SetExpiredAtTime("key", "value", now.Add(time.Hour)) },
+			expectedL1: time.Minute,
+			expectedL2: time.Hour,
+		},
+		{
+			name:       "Time before the end of the L1 TTL",
+			set:        func(c *Tiered, now time.Time) { c.SetExpiredAtTime("key", "value", now.Add(time.Second)) },
+			expectedL1: time.Second,
+			expectedL2: time.Second,
+		},
+		{
+			name:       "Independent TTLs",
+			set:        func(c *Tiered, now time.Time) { c.SetWithTTLs("key", "value", 2*time.Minute, 2*time.Hour) },
+			expectedL1: 2 * time.Minute,
+			expectedL2: 2 * time.Hour,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c, l1, l2 := newTestTiered(time.Minute)
+			now := l1.Clock().Now()
+			test.set(c, now)
+
+			assertExpiresIn(t, "L1", l1.data["key"].Expiration, now, test.expectedL1)
+			assertExpiresIn(t, "L2", l2.data["key"].Expiration, now, test.expectedL2)
+		})
+	}
+}
+
+func assertExpiresIn(t *testing.T, tier string, expiration time.Time, now time.Time, expected time.Duration) {
+	t.Helper()
+	if got := expiration.Sub(now); got != expected {
+		t.Errorf("%s: expected expiration in %v, got %v", tier, expected, got)
+	}
+}
+
+func TestTieredGetKeepsL2Expiration(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	l1 := NewCache(zap.NewNop(), WithClock(clk))
+	l2 := NewCache(zap.NewNop(), WithClock(clk))
+	c := NewTiered(l1, l2, time.Hour)
+	midnight := clk.Now().Add(time.Second)
+	l2.SetExpiredAtTime("prices", "today", midnight)
+
+	if value, found := c.Get("prices"); !found || value != "today" {
+		t.Fatalf("Expected the value of L2, got (%v, %v)", value, found)
+	}
+	if expiration := l1.data["prices"].Expiration; !expiration.Equal(midnight) {
+		t.Errorf("L1 copy should expire with L2 at %v, got %v", midnight, expiration)
+	}
+
+	clk.Advance(10 * time.Minute)
+	if value, found := c.Get("prices"); found {
+		t.Errorf("Value expired in L2 should not be served from L1, got %v", value)
+	}
+}
+
+type storeWithoutTTL struct {
+	Store
+}
+
+func TestTieredGetFromStoreWithoutTTL(t *testing.T) {
+	l1 := NewCache(zap.NewNop())
+	l2 := NewCache(zap.NewNop())
+	c := NewTiered(l1, storeWithoutTTL{l2}, time.Hour)
+	l2.SetExpiredAfterTimePeriod("key", "value", time.Minute)
+
+	if value, found := c.Get("key"); !found || value != "value" {
+		t.Errorf("Expected the value of L2, got (%v, %v)", value, found)
+	}
+	if _, found := l1.Get("key"); found {
+		t.Error("Value with an unknown TTL in L2 should not be copied to L1")
+	}
+}
+
+func TestTieredDelete(t *testing.T) {
+	c, l1, l2 := newTestTiered(time.Minute)
+	for _, key := range []string{"user:1", "user:2", "post:1"} {
+		c.SetExpiredAfterTimePeriod(key, key, time.Hour)
+	}
+
+	c.Delete("post:1")
+	c.DeletePrefix("user:")
+
+	for _, tier := range []*Cache{l1, l2} {
+		if tier.Len() != 0 {
+			t.Errorf("Expected both tiers to be empty, got %v", slices.Collect(tier.Keys()))
+		}
+	}
+}
+
+func TestTieredWithRedis(t *testing.T) {
+	server := startFakeRedis(t, "")
+	shared := newTestRedisStore(t, server, RedisConfig{})
+	first := NewTiered(NewCache(zap.NewNop()), shared, time.Minute)
+	second := NewTiered(NewCache(zap.NewNop()), newTestRedisStore(t, server, RedisConfig{}), time.Minute)
+	first.RegisterType("prices", []price{})
+	second.RegisterType("prices", []price{})
+
+	first.SetExpiredAfterTimePeriod("today", []price{{Hour: 1, Value: 2.5}}, time.Hour)
+
+	value, found := second.Get("today")
+	if !found || !reflect.DeepEqual(value, []price{{Hour: 1, Value: 2.5}}) {
+		t.Errorf("Expected the value written by the other replica, got (%v, %v)", value, found)
+	}
+	if stats := second.TierStats(); stats.L2Hits != 1 {
+		t.Errorf("Expected one L2 hit, got %+v", stats)
+	}
+}
+
+func TestTieredCollector(t *testing.T) {
+	c, l1, _ := newTestTiered(time.Minute)
+	c.SetExpiredAfterTimePeriod("key", "value", time.Hour)
+	c.Get("key")
+	l1.Delete("key")
+	c.Get("key")
+
+	expected := `
+# HELP cache_tier_hits_total Total number of cache lookups which found a value, by tier
+# TYPE cache_tier_hits_total counter
+cache_tier_hits_total{cache="prices",tier="l1"} 1
+cache_tier_hits_total{cache="prices",tier="l2"} 1
+`
+	if err := testutil.CollectAndCompare(NewCollector("prices", c), strings.NewReader(expected), "cache_tier_hits_total"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,227 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	goodsHTTP "github.com/AnhCaooo/go-goods/http"
+	"go.uber.org/zap"
+)
+
+const (
+	// InvalidationSecretHeader carries the shared secret of HTTPTransport
+	InvalidationSecretHeader = "X-Cache-Invalidation-Secret"
+
+	defaultTransportTimeout = 5 * time.Second
+	maxInvalidationBodySize = 64 << 10
+)
+
+// MemoryBus connects MemoryTransports in the same process. It is meant for tests and for running several replicas in one process.
+type MemoryBus struct {
+	lock       sync.RWMutex
+	transports []*MemoryTransport
+}
+
+// MemoryTransport is a Transport delivering invalidations to every transport of its MemoryBus, including itself.
+type MemoryTransport struct {
+	bus      *MemoryBus
+	lock     sync.RWMutex
+	handlers []func(message Invalidation)
+	closed   bool
+}
+
+// NewMemoryBus returns an empty MemoryBus
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{}
+}
+
+// Transport returns a new transport connected to the bus
+//
+// Example usage:
+//
+//	bus := cache.NewMemoryBus()
+//	first := cache.NewReplicatedCache(logger, cache.NewCache(logger), bus.Transport())
+//	second := cache.NewReplicatedCache(logger, cache.NewCache(logger), bus.Transport())
+func (b *MemoryBus) Transport() *MemoryTransport {
+	t := &MemoryTransport{bus: b}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.transports = append(b.transports, t)
+	return t
+}
+
+// Publish delivers the invalidation synchronously to every open transport of the bus
+func (t *MemoryTransport) Publish(ctx context.Context, message Invalidation) error {
+	t.bus.lock.RLock()
+	transports := append([]*MemoryTransport(nil), t.bus.transports...)
+	t.bus.lock.RUnlock()
+
+	for _, transport := range transports {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		transport.deliver(message)
+	}
+	return nil
+}
+
+// Subscribe registers a handler called for every invalidation published on the bus
+func (t *MemoryTransport) Subscribe(handler func(message Invalidation)) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.handlers = append(t.handlers, handler)
+}
+
+// Close stops delivering invalidations to the handlers of this transport
+func (t *MemoryTransport) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.closed = true
+	return nil
+}
+
+func (t *MemoryTransport) deliver(message Invalidation) {
+	t.lock.RLock()
+	handlers := t.handlers
+	if t.closed {
+		handlers = nil
+	}
+	t.lock.RUnlock()
+
+	for _, handler := range handlers {
+		handler(message)
+	}
+}
+
+// HTTPTransportConfig configures an HTTPTransport
+type HTTPTransportConfig struct {
+	Peers  []string     // Peers are the webhook URLs of the other replicas, where their HTTPTransport is mounted
+	Secret string       // Secret is sent with every invalidation and required on every received one. It is mandatory
+	Client *http.Client // Client sends the invalidations. Default is a client with a 5 seconds timeout
+}
+
+// HTTPTransport is a Transport posting invalidations as JSON to webhooks of the other replicas.
+// It is also the http.Handler receiving them, so it has to be mounted on a route reachable by the peers.
+type HTTPTransport struct {
+	logger   *zap.Logger
+	config   HTTPTransportConfig
+	lock     sync.RWMutex
+	handlers []func(message Invalidation)
+	closed   bool
+}
+
+// NewHTTPTransport returns an HTTPTransport publishing to the peers of config.
+// It returns an error if config.Secret is empty: anyone reaching the webhook could otherwise purge the caches of every replica.
+//
+// Example usage:
+//
+//	transport, err := cache.NewHTTPTransport(logger, cache.HTTPTransportConfig{
+//		Peers:  []string{"http://prices-1:8080/internal/cache/invalidate", "http://prices-2:8080/internal/cache/invalidate"},
+//		Secret: os.Getenv("CACHE_INVALIDATION_SECRET"),
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	router.Handle("/internal/cache/invalidate", transport)
+func NewHTTPTransport(logger *zap.Logger, config HTTPTransportConfig) (*HTTPTransport, error) {
+	if config.Secret == "" {
+		return nil, errors.New("failed to create cache invalidation transport: secret is missing")
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: defaultTransportTimeout}
+	}
+	return &HTTPTransport{logger: logger, config: config}, nil
+}
+
+// Publish posts the invalidation to every peer concurrently and returns the errors of the peers which did not accept it
+func (t *HTTPTransport) Publish(ctx context.Context, message Invalidation) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache invalidation: %s", err.Error())
+	}
+
+	errs := make([]error, len(t.config.Peers))
+	var wg sync.WaitGroup
+	for i, peer := range t.config.Peers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = t.post(ctx, peer, body)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (t *HTTPTransport) post(ctx context.Context, peer string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, peer, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create cache invalidation request for %s: %s", peer, err.Error())
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(InvalidationSecretHeader, t.config.Secret)
+
+	resp, err := t.config.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send cache invalidation to %s: %s", peer, err.Error())
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("failed to send cache invalidation to %s: unexpected status %d", peer, resp.StatusCode)
+	}
+	return nil
+}
+
+// Subscribe registers a handler called for every invalidation received by ServeHTTP
+func (t *HTTPTransport) Subscribe(handler func(message Invalidation)) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.handlers = append(t.handlers, handler)
+}
+
+// Close makes ServeHTTP reject further invalidations
+func (t *HTTPTransport) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.closed = true
+	return nil
+}
+
+// ServeHTTP receives an invalidation posted by a peer and passes it to the subscribed handlers
+func (t *HTTPTransport) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		goodsHTTP.Error(w, http.StatusMethodNotAllowed, "only POST is allowed", goodsHTTP.InvalidRequest)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(InvalidationSecretHeader)), []byte(t.config.Secret)) != 1 {
+		goodsHTTP.Error(w, http.StatusUnauthorized, "invalid cache invalidation secret", goodsHTTP.Unauthorized)
+		return
+	}
+
+	var message Invalidation
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxInvalidationBodySize)).Decode(&message); err != nil {
+		goodsHTTP.Error(w, http.StatusBadRequest, fmt.Sprintf("failed to decode cache invalidation: %s", err.Error()), goodsHTTP.InvalidRequest)
+		return
+	}
+
+	t.lock.RLock()
+	handlers, closed := t.handlers, t.closed
+	t.lock.RUnlock()
+	if closed {
+		goodsHTTP.Error(w, http.StatusServiceUnavailable, "cache invalidation transport is closed", goodsHTTP.InternalServer)
+		return
+	}
+
+	for _, handler := range handlers {
+		handler(message)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,306 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/clock"
+	"go.uber.org/zap"
+)
+
+// Typed represents a thread-safe, type-safe in-memory cache with logging capabilities.
+// Keys can be any comparable type and values are returned as V directly,
+// so callers do not need to type-assert the result of Get.
+type Typed[K comparable, V any] struct {
+	data         map[K]Entry[V]
+	logger       *zap.Logger
+	lock         sync.RWMutex
+	maxEntries   int
+	maxBytes     int64
+	bytes        int64 // total size of the values, only measured when maxBytes is set
+	sizer        Sizer
+	evictor      evictor[K] // nil when the cache is unbounded
+	expirations  *expiryQueue[K]
+	janitor      *janitor // nil when the janitor is disabled
+	loadLock     sync.Mutex
+	calls        map[K]*loadCall[V] // GetOrLoad loader calls in flight, guarded by loadLock
+	refreshes    map[K]*loadCall[V] // background refreshes in flight, guarded by loadLock
+	refresher    KeyLoader[K, V]    // guarded by loadLock
+	refreshAhead time.Duration
+	onEvict      []EvictionCallback[K, V] // guarded by lock
+	onExpire     []EvictionCallback[K, V] // guarded by lock
+	removed      []removal[K, V]          // entries removed while holding lock, not yet reported to callbacks
+	prefixes     *prefixIndex             // nil unless K is string
+	tags         map[string]map[K]struct{}
+	keyTags      map[K][]string
+	codec        Codec[V]
+	snapshotKey  []byte
+	stats        counters
+	clock        clock.Clock
+}
+
+// Entry represents a value stored in the cache along with its expiration time.
+// Value is the actual data being cached.
+// Expiration is the time at which the cached value will expire and should be considered invalid.
+// SoftExpiration, if set, is the time after which the value is stale: it is still served but refreshed in the background.
+// MaxExpiration, if set, is the time after which a value with sliding expiration expires even if it is still being read.
+type Entry[V any] struct {
+	Value          V
+	Expiration     time.Time
+	SoftExpiration time.Time
+	MaxExpiration  time.Time
+
+	ttl     time.Duration // duration the value was cached for, used to cache a refreshed value the same way
+	softTTL time.Duration
+	sliding time.Duration // idle duration after which a value with sliding expiration expires, extended by every Get
+	size    int64         // size of the value measured by the Sizer, only set when the cache is bounded by bytes
+}
+
+// NewTyped returns a new Typed cache instance
+//
+// Example usage:
+//
+//	prices := cache.NewTyped[string, []Price](logger, cache.WithMaxEntries(1000))
+//	prices.SetExpiredAfterTimePeriod("2024-01-01", todayPrices, time.Hour)
+//	if value, found := prices.Get("2024-01-01"); found {
+//		// value is already []Price
+//	}
+func NewTyped[K comparable, V any](logger *zap.Logger, opts ...Option) *Typed[K, V] {
+	return newTyped[K, V](logger, make(map[K]Entry[V]), newOptions(opts))
+}
+
+// newTyped builds a Typed cache on top of an existing map, so adapters can share the storage.
+func newTyped[K comparable, V any](logger *zap.Logger, data map[K]Entry[V], o options) *Typed[K, V] {
+	c := &Typed[K, V]{
+		data:         data,
+		logger:       logger,
+		maxEntries:   o.maxEntries,
+		maxBytes:     o.maxBytes,
+		sizer:        o.sizer,
+		expirations:  newExpiryQueue[K](),
+		calls:        make(map[K]*loadCall[V]),
+		refreshes:    make(map[K]*loadCall[V]),
+		refreshAhead: o.refreshAhead,
+		clock:        o.clock,
+		tags:         make(map[string]map[K]struct{}),
+		keyTags:      make(map[K][]string),
+		codec:        JSONCodec[V]{},
+		snapshotKey:  o.snapshotKey,
+	}
+	if _, ok := any(*new(K)).(string); ok {
+		c.prefixes = newPrefixIndex()
+	}
+	if o.maxEntries > 0 || o.maxBytes > 0 {
+		c.evictor = newEvictor[K](o.policy, o.maxEntries)
+	}
+	if o.janitorInterval > 0 {
+		c.janitor = startJanitor(o.janitorCtx, o.janitorInterval, logger, c.purgeExpired)
+	}
+	return c
+}
+
+// SetExpiredAfterTimePeriod adds new key-value pair to the cache.
+// The value expires after the given duration counted from now.
+func (c *Typed[K, V]) SetExpiredAfterTimePeriod(key K, value V, duration time.Duration) {
+	c.lock.Lock()
+	defer c.unlock()
+
+	expirationTime := c.clock.Now().Add(duration)
+	c.store(key, Entry[V]{
+		Value:      value,
+		Expiration: expirationTime,
+		ttl:        duration,
+	})
+}
+
+// SetExpiredAtTime adds new key-value pair to the cache.
+// The value expires at the given point in time.
+func (c *Typed[K, V]) SetExpiredAtTime(key K, value V, expiredTime time.Time) {
+	c.lock.Lock()
+	defer c.unlock()
+
+	c.store(key, Entry[V]{
+		Value:      value,
+		Expiration: expiredTime,
+	})
+}
+
+// Get retrieves a value from the cache by using a key.
+// If the value is still valid, it returns the value and `true`.
+// If the value is missing or expired, it returns the zero value of V and `false`.
+// Expired values are removed from the cache.
+//
+// A stale value, i.e. past its soft expiration but before its expiration, is still returned
+// and triggers one asynchronous refresh through the loader registered with RegisterLoader.
+func (c *Typed[K, V]) Get(key K) (V, bool) {
+	entry, found := c.lookup(key)
+	if !found {
+		var zero V
+		return zero, false
+	}
+	if c.needsRefresh(entry, c.clock.Now()) {
+		c.refresh(key, entry)
+	}
+	return entry.Value, true
+}
+
+// lookup returns the entry of key if it has not expired yet
+func (c *Typed[K, V]) lookup(key K) (Entry[V], bool) {
+	// fast path: a hit in an unbounded cache only needs the read lock, unless its expiration has to slide
+	c.lock.RLock()
+	value, exists := c.data[key]
+	if exists && c.evictor == nil && value.sliding == 0 && !c.clock.Now().After(value.Expiration) {
+		c.lock.RUnlock()
+		c.stats.hits.Add(1)
+		return value, true
+	}
+	c.lock.RUnlock()
+
+	c.lock.Lock()
+	defer c.unlock()
+
+	now := c.clock.Now()
+	value, exists = c.data[key]
+	if !exists {
+		c.logger.Debug("[go-goods] cache key was not found from cache", zap.Any("key", key))
+		c.stats.misses.Add(1)
+		return Entry[V]{}, false
+	}
+	if now.After(value.Expiration) {
+		c.logger.Debug("[go-goods] cache was expired",
+			zap.Any("key", key),
+			zap.Time("expiration-time", value.Expiration),
+		)
+		c.remove(key, ReasonExpired)
+		c.stats.misses.Add(1)
+		return Entry[V]{}, false
+	}
+	if c.evictor != nil {
+		c.evictor.access(key)
+	}
+	if value.sliding > 0 {
+		value = c.slide(key, value, now)
+	}
+	c.stats.hits.Add(1)
+	return value, true
+}
+
+// peek returns the entry of key if it has not expired yet, without removing expired entries or recording usage
+func (c *Typed[K, V]) peek(key K) (Entry[V], bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	entry, exists := c.data[key]
+	if !exists || c.clock.Now().After(entry.Expiration) {
+		return Entry[V]{}, false
+	}
+	return entry, true
+}
+
+// Delete cache based on receiving cache key. If key is not valid, then Delete is no-op
+func (c *Typed[K, V]) Delete(key K) {
+	c.lock.Lock()
+	defer c.unlock()
+	c.remove(key, ReasonDeleted)
+}
+
+// DeleteFunc removes all cache entries whose key matches the given predicate.
+// Removed entries are reported to eviction callbacks with ReasonDeleteAll.
+func (c *Typed[K, V]) DeleteFunc(match func(key K) bool) {
+	c.lock.Lock()
+	defer c.unlock()
+
+	for k := range c.data {
+		if match(k) {
+			c.remove(k, ReasonDeleteAll)
+		}
+	}
+}
+
+// store saves the entry, evicting other entries first when a new key would exceed the capacity
+// or the new value would exceed the byte budget. Overwriting an entry drops its tags. The caller must hold the lock.
+func (c *Typed[K, V]) store(key K, entry Entry[V]) {
+	tracked := true
+	if c.maxBytes > 0 {
+		entry.size = c.sizer.Size(entry.Value)
+		if entry.size > c.maxBytes {
+			c.logger.Debug("[go-goods] cache value is larger than the cache and was not stored",
+				zap.Any("key", key),
+				zap.Int64("size", entry.size),
+			)
+			c.remove(key, ReasonCapacity)
+			return
+		}
+		tracked = c.evictForBytes(key, entry.size)
+	}
+
+	old, exists := c.data[key]
+	if exists {
+		c.untag(key)
+		if c.evictor != nil && tracked {
+			c.evictor.access(key)
+		} else if c.evictor != nil {
+			c.evictor.add(key)
+		}
+	} else {
+		if c.evictor != nil {
+			for c.maxEntries > 0 && len(c.data) >= c.maxEntries {
+				victim, ok := c.evictor.victim()
+				if !ok {
+					break
+				}
+				c.logger.Debug("[go-goods] cache entry was evicted due to capacity", zap.Any("key", victim))
+				c.remove(victim, ReasonCapacity)
+			}
+			c.evictor.add(key)
+		}
+		if c.prefixes != nil {
+			c.prefixes.insert(any(key).(string))
+		}
+	}
+	c.data[key] = entry
+	c.bytes += entry.size - old.size
+	c.expirations.set(key, entry.Expiration)
+}
+
+// evictForBytes evicts entries until a value of size bytes fits in the byte budget.
+// The current value of key does not count, since it is about to be replaced. If that value is chosen as victim,
+// it is kept until it is replaced but is no longer tracked by the evictor, which is reported by returning false.
+func (c *Typed[K, V]) evictForBytes(key K, size int64) (tracked bool) {
+	tracked = true
+	for c.bytes-c.data[key].size+size > c.maxBytes {
+		victim, ok := c.evictor.victim()
+		if !ok {
+			break
+		}
+		if victim == key {
+			tracked = false
+			continue
+		}
+		c.logger.Debug("[go-goods] cache entry was evicted due to size", zap.Any("key", victim))
+		c.remove(victim, ReasonCapacity)
+	}
+	return tracked
+}
+
+// remove deletes the entry and forgets its usage history and expiration.
+// The caller must hold the lock and release it with unlock, so that eviction callbacks are notified.
+func (c *Typed[K, V]) remove(key K, reason EvictionReason) {
+	entry, exists := c.data[key]
+	if exists {
+		c.stats.countRemoval(reason)
+		c.bytes -= entry.size
+	}
+	if exists && (len(c.onEvict) > 0 || len(c.onExpire) > 0) {
+		c.removed = append(c.removed, removal[K, V]{key: key, value: entry.Value, reason: reason})
+	}
+	delete(c.data, key)
+	c.expirations.remove(key)
+	c.untag(key)
+	if c.evictor != nil {
+		c.evictor.remove(key)
+	}
+	if c.prefixes != nil {
+		c.prefixes.delete(any(key).(string))
+	}
+}
@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/clock"
+	"go.uber.org/zap"
+)
+
+type price struct {
+	Hour  int
+	Value float64
+}
+
+func TestTypedGet(t *testing.T) {
+	tests := []struct {
+		name           string
+		key            string
+		value          []price
+		duration       time.Duration
+		elapsed        time.Duration
+		expectedExists bool
+	}{
+		{
+			name:           "Get valid non-expired value",
+			key:            "2024-01-01",
+			value:          []price{{Hour: 1, Value: 2.5}},
+			duration:       time.Hour,
+			elapsed:        59 * time.Minute,
+			expectedExists: true,
+		},
+		{
+			name:           "Get value at its expiration",
+			key:            "2024-01-01",
+			value:          []price{{Hour: 1, Value: 2.5}},
+			duration:       time.Hour,
+			elapsed:        time.Hour,
+			expectedExists: true,
+		},
+		{
+			name:           "Get expired value",
+			key:            "2024-01-02",
+			value:          []price{{Hour: 2, Value: 3.5}},
+			duration:       time.Hour,
+			elapsed:        time.Hour + time.Nanosecond,
+			expectedExists: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clk := clock.NewFake(time.Now())
+			c := NewTyped[string, []price](zap.NewNop(), WithClock(clk))
+			c.SetExpiredAfterTimePeriod(test.key, test.value, test.duration)
+			clk.Advance(test.elapsed)
+
+			value, exists := c.Get(test.key)
+			if exists != test.expectedExists {
+				t.Fatalf("Expected exists=%v, got %v", test.expectedExists, exists)
+			}
+			if !exists {
+				if value != nil {
+					t.Errorf("Expected zero value, got %v", value)
+				}
+				if _, stillExists := c.data[test.key]; stillExists {
+					t.Errorf("Expired key %q should have been deleted", test.key)
+				}
+				return
+			}
+			if len(value) != len(test.value) || value[0] != test.value[0] {
+				t.Errorf("Expected value %v, got %v", test.value, value)
+			}
+		})
+	}
+}
+
+func TestTypedNonStringKey(t *testing.T) {
+	c := NewTyped[int, string](zap.NewNop())
+	c.SetExpiredAtTime(1, "one", time.Now().Add(time.Hour))
+	c.SetExpiredAtTime(2, "two", time.Now().Add(time.Hour))
+
+	if value, exists := c.Get(1); !exists || value != "one" {
+		t.Errorf("Expected (one, true), got (%q, %v)", value, exists)
+	}
+
+	c.Delete(1)
+	if _, exists := c.Get(1); exists {
+		t.Error("Key 1 should have been deleted")
+	}
+
+	c.DeleteFunc(func(key int) bool { return key%2 == 0 })
+	if _, exists := c.Get(2); exists {
+		t.Error("Key 2 should have been deleted by DeleteFunc")
+	}
+}
+
+func TestCacheSharesStorageWithTyped(t *testing.T) {
+	c := NewCache(zap.NewNop())
+	c.SetExpiredAfterTimePeriod("key", "value", time.Minute)
+
+	if _, exists := c.Data["key"]; !exists {
+		t.Error("Value set through Cache should be visible in Data")
+	}
+
+	c.Data["direct"] = CacheValue{Value: 42, Expiration: time.Now().Add(time.Minute)}
+	if value, exists := c.Get("direct"); !exists || value != 42 {
+		t.Errorf("Expected (42, true), got (%v, %v)", value, exists)
+	}
+}
@@ -0,0 +1,60 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the current time. Code reading the time through a Clock can be tested with Fake instead of sleeping.
+type Clock interface {
+	Now() time.Time
+}
+
+// Real is the Clock of the system, which returns time.Now()
+type Real struct{}
+
+// Now returns the current system time
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// Fake is a Clock which only moves when told to, so tests can control time precisely.
+// It is safe for concurrent use.
+//
+// Example usage:
+//
+//	clk := clock.NewFake(time.Date(2024, 1, 1, 23, 59, 0, 0, time.UTC))
+//	c := cache.NewCache(logger, cache.WithClock(clk))
+//	c.SetExpiredAfterTimePeriod("key", "value", time.Minute)
+//	clk.Advance(2 * time.Minute)
+//	_, found := c.Get("key") // false
+type Fake struct {
+	lock sync.RWMutex
+	now  time.Time
+}
+
+// NewFake returns a Fake clock set to now
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+// Now returns the time the clock is set to
+func (f *Fake) Now() time.Time {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return f.now
+}
+
+// Advance moves the clock forward by d, or backward if d is negative
+func (f *Fake) Advance(d time.Duration) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// Set moves the clock to now
+func (f *Fake) Set(now time.Time) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.now = now
+}
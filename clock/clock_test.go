@@ -0,0 +1,57 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReal(t *testing.T) {
+	before := time.Now()
+	now := Real{}.Now()
+	after := time.Now()
+
+	if now.Before(before) || now.After(after) {
+		t.Errorf("Real.Now() = %v; want between %v and %v", now, before, after)
+	}
+}
+
+func TestFake(t *testing.T) {
+	start := time.Date(2024, 1, 1, 23, 59, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		act      func(f *Fake)
+		expected time.Time
+	}{
+		{
+			name:     "Does not move by itself",
+			act:      func(f *Fake) {},
+			expected: start,
+		},
+		{
+			name:     "Advance",
+			act:      func(f *Fake) { f.Advance(2 * time.Minute) },
+			expected: time.Date(2024, 1, 2, 0, 1, 0, 0, time.UTC),
+		},
+		{
+			name:     "Advance backward",
+			act:      func(f *Fake) { f.Advance(-time.Hour) },
+			expected: time.Date(2024, 1, 1, 22, 59, 0, 0, time.UTC),
+		},
+		{
+			name:     "Set",
+			act:      func(f *Fake) { f.Set(time.Date(2030, 6, 15, 12, 0, 0, 0, time.UTC)) },
+			expected: time.Date(2030, 6, 15, 12, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := NewFake(start)
+			test.act(f)
+			if now := f.Now(); !now.Equal(test.expected) {
+				t.Errorf("Now() = %v; want %v", now, test.expected)
+			}
+		})
+	}
+}
@@ -86,6 +86,40 @@ func EncryptFile(key []byte, decryptedFilePath, encryptedFilePath string) error
 	return nil
 }
 
+// Encrypt encrypts data in memory with AES-GCM using the provided key, the same way as EncryptFile does.
+// The random nonce is prepended to the returned cipher text.
+//
+// Example usage:
+//
+//	cipherText, err := crypto.Encrypt(key, plainText)
+//	if err != nil {
+//		return err
+//	}
+//
+// Parameters:
+//   - key: The ENCRYPTION KEY, 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256.
+//   - plainText: The data to encrypt.
+//
+// Returns:
+//   - []byte: The nonce followed by the encrypted data.
+//   - error: An ERROR if the key is invalid or the nonce cannot be generated.
+func Encrypt(key []byte, plainText []byte) ([]byte, error) {
+	return encryptAES(key, plainText)
+}
+
+// Decrypt decrypts data produced by Encrypt or EncryptFile using the provided key.
+//
+// Parameters:
+//   - key: The DECRYPTION KEY used to encrypt the data.
+//   - cipherText: The nonce followed by the encrypted data.
+//
+// Returns:
+//   - []byte: The decrypted data.
+//   - error: An ERROR if the key is invalid or the data cannot be authenticated.
+func Decrypt(key []byte, cipherText []byte) ([]byte, error) {
+	return decryptAES(key, cipherText)
+}
+
 // AES-GCM encryption
 func encryptAES(key []byte, plainText []byte) ([]byte, error) {
 	// Creating block of algorithm
@@ -3,6 +3,8 @@ package helpers
 import (
 	"sync"
 	"time"
+
+	"github.com/AnhCaooo/go-goods/clock"
 )
 
 const (
@@ -15,8 +17,36 @@ var (
 	helsinkiLocation *time.Location
 	helsinkiOnce     sync.Once
 	helsinkiErr      error
+
+	appClock     clock.Clock = clock.Real{}
+	appClockLock sync.RWMutex
 )
 
+// SetClock replaces the clock the time helpers read the current time from and returns the previous one,
+// so tests can fix the date and restore the system clock afterwards.
+// The clock is global to the process: code which runs in parallel tests or needs its own clock
+// passes it to the variants taking a clock.Clock, e.g. GetTodayDateAt, instead.
+//
+// Example usage:
+//
+//	previous := helpers.SetClock(clock.NewFake(time.Date(2024, 12, 31, 23, 59, 0, 0, time.Local)))
+//	defer helpers.SetClock(previous)
+//	helpers.GetTomorrowDate() // "2025-01-01"
+func SetClock(c clock.Clock) clock.Clock {
+	appClockLock.Lock()
+	defer appClockLock.Unlock()
+	previous := appClock
+	appClock = c
+	return previous
+}
+
+// currentClock returns the application's clock
+func currentClock() clock.Clock {
+	appClockLock.RLock()
+	defer appClockLock.RUnlock()
+	return appClock
+}
+
 // SetTime returns current date based on application's time with specific hour and minute.
 // Parameters:
 //   - hour: The hour to set (0-23).
@@ -26,7 +56,12 @@ var (
 //   - time.Time: The time set to the specified hour and minute in UTC.
 //   - error: An error if the Helsinki timezone could not be loaded.
 func SetTime(hour int, minute int) (time.Time, error) {
-	year, month, day := time.Now().Date()
+	return SetTimeAt(currentClock(), hour, minute)
+}
+
+// SetTimeAt is SetTime reading the current date from c instead of the application's clock
+func SetTimeAt(c clock.Clock, hour int, minute int) (time.Time, error) {
+	year, month, day := c.Now().Date()
 	settingTime := time.Date(year, month, day, hour, minute, 0, 0, time.Local)
 	return settingTime, nil
 }
@@ -43,21 +78,38 @@ func LoadHelsinkiLocation() (*time.Location, error) {
 
 // getTodayDate returns date of today in "YYYY-MM-DD" format
 func GetTodayDate() string {
-	today := time.Now()
-	return today.Format(DATE_FORMAT)
+	return GetTodayDateAt(currentClock())
+}
+
+// GetTodayDateAt returns date of today according to c in "YYYY-MM-DD" format
+//
+// Example usage:
+//
+//	clk := clock.NewFake(time.Date(2024, 12, 31, 23, 59, 0, 0, time.Local))
+//	helpers.GetTodayDateAt(clk) // "2024-12-31"
+func GetTodayDateAt(c clock.Clock) string {
+	return c.Now().Format(DATE_FORMAT)
 }
 
 // GetTomorrowDate returns date of tomorrow in "YYYY-MM-DD" format
 func GetTomorrowDate() string {
-	today := time.Now()
-	tomorrow := today.AddDate(0, 0, 1)
+	return GetTomorrowDateAt(currentClock())
+}
+
+// GetTomorrowDateAt returns date of tomorrow according to c in "YYYY-MM-DD" format
+func GetTomorrowDateAt(c clock.Clock) string {
+	tomorrow := c.Now().AddDate(0, 0, 1)
 	return tomorrow.Format(DATE_FORMAT)
 }
 
 // getYesterdayDate returns date of yesterday in "YYYY-MM-DD" format
 func GetYesterdayDate() string {
-	today := time.Now()
-	yesterday := today.AddDate(0, 0, -1)
+	return GetYesterdayDateAt(currentClock())
+}
+
+// GetYesterdayDateAt returns date of yesterday according to c in "YYYY-MM-DD" format
+func GetYesterdayDateAt(c clock.Clock) string {
+	yesterday := c.Now().AddDate(0, 0, -1)
 	return yesterday.Format(DATE_FORMAT)
 }
 
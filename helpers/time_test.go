@@ -2,6 +2,9 @@ package helpers
 
 import (
 	"testing"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/clock"
 )
 
 func TestParseHour(t *testing.T) {
@@ -59,3 +62,91 @@ func TestParseHourMinute(t *testing.T) {
 		}
 	}
 }
+
+func TestDateHelpers(t *testing.T) {
+	tests := []struct {
+		name              string
+		now               time.Time
+		expectedToday     string
+		expectedTomorrow  string
+		expectedYesterday string
+	}{
+		{"middle of the day", time.Date(2024, 6, 15, 12, 0, 0, 0, time.Local), "2024-06-15", "2024-06-16", "2024-06-14"},
+		{"just after midnight", time.Date(2024, 6, 15, 0, 0, 0, 0, time.Local), "2024-06-15", "2024-06-16", "2024-06-14"},
+		{"just before midnight", time.Date(2024, 6, 15, 23, 59, 59, 0, time.Local), "2024-06-15", "2024-06-16", "2024-06-14"},
+		{"end of year", time.Date(2024, 12, 31, 23, 59, 0, 0, time.Local), "2024-12-31", "2025-01-01", "2024-12-30"},
+		{"leap day", time.Date(2024, 3, 1, 8, 0, 0, 0, time.Local), "2024-03-01", "2024-03-02", "2024-02-29"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := SetClock(clock.NewFake(tt.now))
+			defer SetClock(previous)
+
+			if today := GetTodayDate(); today != tt.expectedToday {
+				t.Errorf("GetTodayDate() = %q; want %q", today, tt.expectedToday)
+			}
+			if tomorrow := GetTomorrowDate(); tomorrow != tt.expectedTomorrow {
+				t.Errorf("GetTomorrowDate() = %q; want %q", tomorrow, tt.expectedTomorrow)
+			}
+			if yesterday := GetYesterdayDate(); yesterday != tt.expectedYesterday {
+				t.Errorf("GetYesterdayDate() = %q; want %q", yesterday, tt.expectedYesterday)
+			}
+		})
+	}
+}
+
+func TestDateHelpersAt(t *testing.T) {
+	// the variants taking a clock do not touch the global clock, so they can run in parallel
+	t.Parallel()
+	clk := clock.NewFake(time.Date(2024, 12, 31, 23, 59, 59, 0, time.Local))
+
+	if today := GetTodayDateAt(clk); today != "2024-12-31" {
+		t.Errorf("GetTodayDateAt() = %q; want %q", today, "2024-12-31")
+	}
+	if tomorrow := GetTomorrowDateAt(clk); tomorrow != "2025-01-01" {
+		t.Errorf("GetTomorrowDateAt() = %q; want %q", tomorrow, "2025-01-01")
+	}
+	if yesterday := GetYesterdayDateAt(clk); yesterday != "2024-12-30" {
+		t.Errorf("GetYesterdayDateAt() = %q; want %q", yesterday, "2024-12-30")
+	}
+	got, err := SetTimeAt(clk, 7, 45)
+	if err != nil {
+		t.Fatalf("SetTimeAt() error = %v", err)
+	}
+	if expected := time.Date(2024, 12, 31, 7, 45, 0, 0, time.Local); !got.Equal(expected) {
+		t.Errorf("SetTimeAt(7, 45) = %v; want %v", got, expected)
+	}
+	clk.Advance(time.Second)
+	if today := GetTodayDateAt(clk); today != "2025-01-01" {
+		t.Errorf("GetTodayDateAt() after midnight = %q; want %q", today, "2025-01-01")
+	}
+}
+
+func TestDateHelpersAcrossMidnight(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 6, 15, 23, 59, 59, 0, time.Local))
+	previous := SetClock(clk)
+	defer SetClock(previous)
+
+	if today := GetTodayDate(); today != "2024-06-15" {
+		t.Fatalf("GetTodayDate() = %q; want %q", today, "2024-06-15")
+	}
+	clk.Advance(time.Second)
+	if today := GetTodayDate(); today != "2024-06-16" {
+		t.Errorf("GetTodayDate() after midnight = %q; want %q", today, "2024-06-16")
+	}
+}
+
+func TestSetTime(t *testing.T) {
+	previous := SetClock(clock.NewFake(time.Date(2024, 6, 15, 23, 30, 0, 0, time.Local)))
+	defer SetClock(previous)
+
+	got, err := SetTime(7, 45)
+	if err != nil {
+		t.Fatalf("SetTime() error = %v", err)
+	}
+	expected := time.Date(2024, 6, 15, 7, 45, 0, 0, time.Local)
+	if !got.Equal(expected) {
+		t.Errorf("SetTime(7, 45) = %v; want %v", got, expected)
+	}
+}
@@ -9,6 +9,9 @@ const (
 	UnauthorizedHeader TranslationKey = "error_no_authorization_header"
 	InternalServer     TranslationKey = "error_internal_server"
 	VerifyToken        TranslationKey = "error_verify_token"
+	TokenExpired       TranslationKey = "error_token_expired"
 	ExtractToken       TranslationKey = "error_extract_token"
 	NotFound           TranslationKey = "error_not_found"
+	CacheEntryNotFound TranslationKey = "error_cache_entry_not_found"
+	EncodeCacheEntry   TranslationKey = "error_encode_cache_entry"
 )
@@ -2,15 +2,52 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/AnhCaooo/go-goods/auth"
 	goodsContext "github.com/AnhCaooo/go-goods/context"
 	goodsHTTP "github.com/AnhCaooo/go-goods/http"
+	"github.com/golang-jwt/jwt/v5"
 )
 
+// Authenticate verifies the HMAC signed bearer token of every request whose path does not start with one of byPassPaths,
+// and adds the user of the token to the request context.
+// The verifier is built once; if jwtSecret is rejected, e.g. because it is empty, every authenticated request is answered with 401.
 func Authenticate(next http.Handler, byPassPaths []string, jwtSecret string) http.Handler {
+	verifier, err := auth.NewVerifier(jwtSecret, auth.HMACAlgorithms...)
+	if err != nil {
+		return authenticate(next, byPassPaths, func(tokenString string) (*jwt.Token, error) {
+			return nil, err
+		})
+	}
+	return authenticate(next, byPassPaths, verifier.Verify)
+}
+
+// AuthenticateWithOptions works like Authenticate but verifies tokens with a verifier built from options,
+// e.g. to also check the issuer, audience and required claims. It returns an error if options are invalid.
+//
+// Example usage:
+//
+//	handler, err := middleware.AuthenticateWithOptions(router, []string{"/health"}, auth.VerifierOptions{
+//		Secret:   jwtSecret,
+//		Issuers:  []string{"https://auth.example.com"},
+//		Audience: "authenticated",
+//		Leeway:   30 * time.Second,
+//	})
+//	if err != nil {
+//		return err
+//	}
+func AuthenticateWithOptions(next http.Handler, byPassPaths []string, options auth.VerifierOptions) (http.Handler, error) {
+	verifier, err := auth.NewVerifierWithOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	return authenticate(next, byPassPaths, verifier.Verify), nil
+}
+
+func authenticate(next http.Handler, byPassPaths []string, verify func(tokenString string) (*jwt.Token, error)) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if shouldBypassAuthentication(r.URL.Path, byPassPaths) {
 			next.ServeHTTP(w, r)
@@ -24,9 +61,13 @@ func Authenticate(next http.Handler, byPassPaths []string, jwtSecret string) htt
 		}
 
 		tokenString = strings.Replace(tokenString, "Bearer ", "", 1)
-		token, err := auth.VerifyToken(tokenString, jwtSecret)
+		token, err := verify(tokenString)
+		if errors.Is(err, auth.ErrTokenExpired) {
+			goodsHTTP.Error(w, http.StatusUnauthorized, "Token is expired", goodsHTTP.TokenExpired)
+			return
+		}
 		if err != nil {
-			goodsHTTP.Error(w, http.StatusUnauthorized, "Failed to verify token", goodsHTTP.VerifyToken)
+			goodsHTTP.Error(w, http.StatusUnauthorized, verifyErrorMessage(err), goodsHTTP.VerifyToken)
 			return
 		}
 
@@ -54,6 +95,24 @@ func Authenticate(next http.Handler, byPassPaths []string, jwtSecret string) htt
 	})
 }
 
+// verifyErrorMessage describes which rule a token failed
+func verifyErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, auth.ErrIssuerInvalid):
+		return "Token issuer is not accepted"
+	case errors.Is(err, auth.ErrAudienceInvalid):
+		return "Token audience is not accepted"
+	case errors.Is(err, auth.ErrClaimMissing):
+		return "Token is missing a required claim"
+	case errors.Is(err, auth.ErrTokenTooOld):
+		return "Token is too old"
+	case errors.Is(err, auth.ErrTokenNotValidYet):
+		return "Token is not valid yet"
+	default:
+		return "Failed to verify token"
+	}
+}
+
 // shouldBypassAuthentication checks if the request path should bypass authentication (do not need authentication)
 func shouldBypassAuthentication(path string, byPassPaths []string) bool {
 	for _, p := range byPassPaths {
@@ -0,0 +1,206 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/auth"
+	goodsContext "github.com/AnhCaooo/go-goods/context"
+	goodsHTTP "github.com/AnhCaooo/go-goods/http"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret"
+
+func signTestToken(t *testing.T, secret string, expiration time.Time) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":        "user-1",
+		"session_id": "session-1",
+		"exp":        expiration.Unix(),
+	}).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+func TestAuthenticate(t *testing.T) {
+	tests := []struct {
+		name                   string
+		path                   string
+		emptySecret            bool
+		authorization          string
+		expectedStatus         int
+		expectedTranslationKey goodsHTTP.TranslationKey
+	}{
+		{
+			name:           "Valid token",
+			path:           "/prices",
+			authorization:  "Bearer " + signTestToken(t, testSecret, time.Now().Add(time.Hour)),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Bypassed path",
+			path:           "/health/live",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:                   "Missing header",
+			path:                   "/prices",
+			expectedStatus:         http.StatusForbidden,
+			expectedTranslationKey: goodsHTTP.UnauthorizedHeader,
+		},
+		{
+			name:                   "Expired token",
+			path:                   "/prices",
+			authorization:          "Bearer " + signTestToken(t, testSecret, time.Now().Add(-time.Minute)),
+			expectedStatus:         http.StatusUnauthorized,
+			expectedTranslationKey: goodsHTTP.TokenExpired,
+		},
+		{
+			name:                   "Forged token",
+			path:                   "/prices",
+			authorization:          "Bearer " + signTestToken(t, "forged", time.Now().Add(time.Hour)),
+			expectedStatus:         http.StatusUnauthorized,
+			expectedTranslationKey: goodsHTTP.VerifyToken,
+		},
+		{
+			name:                   "Empty secret rejects every token",
+			path:                   "/prices",
+			emptySecret:            true,
+			authorization:          "Bearer " + signTestToken(t, testSecret, time.Now().Add(time.Hour)),
+			expectedStatus:         http.StatusUnauthorized,
+			expectedTranslationKey: goodsHTTP.VerifyToken,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var userCtx goodsContext.UserContext
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				userCtx, _ = r.Context().Value(goodsContext.ContextKey).(goodsContext.UserContext)
+			})
+			r := httptest.NewRequest(http.MethodGet, test.path, nil)
+			if test.authorization != "" {
+				r.Header.Set("Authorization", test.authorization)
+			}
+			secret := testSecret
+			if test.emptySecret {
+				secret = ""
+			}
+			recorder := httptest.NewRecorder()
+			Authenticate(next, []string{"/health"}, secret).ServeHTTP(recorder, r)
+
+			if recorder.Code != test.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", test.expectedStatus, recorder.Code)
+			}
+			if test.expectedStatus != http.StatusOK {
+				var httpError goodsHTTP.HTTPError
+				if err := json.NewDecoder(recorder.Body).Decode(&httpError); err != nil {
+					t.Fatalf("failed to decode error response: %v", err)
+				}
+				if httpError.TranslationKey != test.expectedTranslationKey {
+					t.Errorf("Expected translation key %q, got %q", test.expectedTranslationKey, httpError.TranslationKey)
+				}
+				return
+			}
+			if test.authorization != "" && (userCtx.UserID != "user-1" || userCtx.SessionID != "session-1") {
+				t.Errorf("Expected user context of user-1, got %+v", userCtx)
+			}
+		})
+	}
+}
+
+func TestAuthenticateWithOptions(t *testing.T) {
+	options := auth.VerifierOptions{
+		Secret:   testSecret,
+		Issuers:  []string{"https://auth.example.com"},
+		Audience: "authenticated",
+	}
+	sign := func(claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		return "Bearer " + token
+	}
+	claims := func(issuer string, expiration time.Time) jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub":        "user-1",
+			"session_id": "session-1",
+			"iss":        issuer,
+			"aud":        "authenticated",
+			"exp":        expiration.Unix(),
+		}
+	}
+
+	tests := []struct {
+		name                   string
+		authorization          string
+		expectedStatus         int
+		expectedTranslationKey goodsHTTP.TranslationKey
+		expectedMessage        string
+	}{
+		{
+			name:           "Valid token",
+			authorization:  sign(claims("https://auth.example.com", time.Now().Add(time.Hour))),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:                   "Expired token",
+			authorization:          sign(claims("https://auth.example.com", time.Now().Add(-time.Minute))),
+			expectedStatus:         http.StatusUnauthorized,
+			expectedTranslationKey: goodsHTTP.TokenExpired,
+			expectedMessage:        "Token is expired",
+		},
+		{
+			name:                   "Unknown issuer",
+			authorization:          sign(claims("https://evil.example.com", time.Now().Add(time.Hour))),
+			expectedStatus:         http.StatusUnauthorized,
+			expectedTranslationKey: goodsHTTP.VerifyToken,
+			expectedMessage:        "Token issuer is not accepted",
+		},
+		{
+			name:                   "Token without audience",
+			authorization:          "Bearer " + signTestToken(t, testSecret, time.Now().Add(time.Hour)),
+			expectedStatus:         http.StatusUnauthorized,
+			expectedTranslationKey: goodsHTTP.VerifyToken,
+			expectedMessage:        "Token is missing a required claim",
+		},
+	}
+
+	handler, err := AuthenticateWithOptions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), nil, options)
+	if err != nil {
+		t.Fatalf("failed to create middleware: %v", err)
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/prices", nil)
+			r.Header.Set("Authorization", test.authorization)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, r)
+
+			if recorder.Code != test.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", test.expectedStatus, recorder.Code)
+			}
+			if test.expectedStatus == http.StatusOK {
+				return
+			}
+			var httpError goodsHTTP.HTTPError
+			if err := json.NewDecoder(recorder.Body).Decode(&httpError); err != nil {
+				t.Fatalf("failed to decode error response: %v", err)
+			}
+			if httpError.TranslationKey != test.expectedTranslationKey || httpError.Message != test.expectedMessage {
+				t.Errorf("Expected %q (%q), got %q (%q)", test.expectedMessage, test.expectedTranslationKey, httpError.Message, httpError.TranslationKey)
+			}
+		})
+	}
+
+	if _, err := AuthenticateWithOptions(http.NotFoundHandler(), nil, auth.VerifierOptions{}); err == nil {
+		t.Error("Expected an error for options without a key")
+	}
+}
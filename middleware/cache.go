@@ -0,0 +1,366 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/cache"
+	goodsContext "github.com/AnhCaooo/go-goods/context"
+)
+
+// defaultMaxResponseBodySize is the largest response body cached when ResponseCacheConfig.MaxBodySize is not set
+const defaultMaxResponseBodySize = 1 << 20
+
+// responseCacheKeyPrefix is prepended to every key the response cache writes, so it can share a cache with other data
+const responseCacheKeyPrefix = "response-cache:"
+
+// CacheRule sets the TTL of responses to requests whose path starts with Prefix.
+// A TTL <= 0 disables caching for the matching paths.
+type CacheRule struct {
+	Prefix string
+	TTL    time.Duration
+}
+
+// ResponseCacheConfig configures the ResponseCache middleware.
+type ResponseCacheConfig struct {
+	// TTL is how long a response is cached when no rule matches its path and it sets no max-age. A TTL <= 0 caches nothing by default.
+	TTL time.Duration
+	// Rules override TTL for paths matching their prefix. The longest matching prefix wins.
+	Rules []CacheRule
+	// PerUser adds the UserID of the goodsContext.UserContext set by Authenticate to the cache key,
+	// so responses of authenticated requests are only served to the same user. Authenticate has to run before ResponseCache.
+	// Requests without a user, e.g. on paths bypassed by Authenticate, are never cached when PerUser is set.
+	PerUser bool
+	// MaxBodySize is the size in bytes of the largest response body which is cached, default is 1 MiB.
+	// A larger response is still written to the client, but stops being buffered once it exceeds the limit.
+	MaxBodySize int64
+}
+
+// cachedResponse is a complete response stored in the cache
+type cachedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+	stored time.Time
+}
+
+// cachedVariants records which request headers, listed by the Vary header of the response, select the cached response of a request
+type cachedVariants struct {
+	headers []string
+}
+
+// ResponseCache Middleware caches complete responses (status, headers and body) of GET and HEAD requests in c,
+// keyed by method, path, query and, if config.PerUser is set, the authenticated user.
+//
+// It honours Cache-Control: a request with no-store bypasses the cache, and a request with no-cache or max-age
+// only gets a cached response which is fresh enough. A response with no-store, no-cache, private (unless PerUser is set),
+// Set-Cookie or Vary: * is not cached, and s-maxage or max-age of the response override the configured TTL.
+// Responses which vary by request headers listed in Vary are cached once per combination of their values.
+// Cached responses are marked with the X-Cache and Age headers. Ages and freshness are measured with the clock of c.
+// Only the bodies of cacheable responses up to config.MaxBodySize are buffered.
+//
+// Example usage:
+//
+//	responses := cache.NewCache(logger, cache.WithMaxBytes(64<<20), cache.WithSizer(middleware.ResponseSizer))
+//	router.Use(middleware.ResponseCache(responses, middleware.ResponseCacheConfig{
+//		TTL: time.Minute,
+//		Rules: []middleware.CacheRule{
+//			{Prefix: "/v1/prices", TTL: 10 * time.Minute},
+//			{Prefix: "/v1/settings", TTL: 0}, // never cached
+//		},
+//		PerUser: true,
+//	}))
+func ResponseCache(c *cache.Cache, config ResponseCacheConfig) func(http.Handler) http.Handler {
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = defaultMaxResponseBodySize
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			requestDirectives := parseCacheControl(r.Header.Get("Cache-Control"))
+			if _, noStore := requestDirectives["no-store"]; noStore {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			key, ok := responseCacheKey(r, config.PerUser)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			now := c.Clock().Now()
+			if response, found := lookupResponse(c, key, r); found && acceptsCached(requestDirectives, response, now) {
+				writeCachedResponse(w, r, response, now)
+				return
+			}
+
+			recorder := &responseRecorder{
+				ResponseWriter: w,
+				status:         http.StatusOK,
+				maxBody:        config.MaxBodySize,
+				ttlOf: func(rec *responseRecorder) time.Duration {
+					return responseTTL(rec, r, config)
+				},
+			}
+			next.ServeHTTP(recorder, r)
+			if !recorder.wroteHeader {
+				recorder.WriteHeader(http.StatusOK)
+			}
+
+			if recorder.ttl <= 0 {
+				return
+			}
+			storeResponse(c, key, r, recorder, recorder.ttl)
+		})
+	}
+}
+
+// ResponseSizer measures cached responses by the size of their body and headers, for caches bounded by cache.WithMaxBytes
+var ResponseSizer cache.Sizer = cache.SizerFunc(func(value interface{}) int64 {
+	response, ok := value.(*cachedResponse)
+	if !ok {
+		return cache.DefaultSizer.Size(value)
+	}
+	size := int64(len(response.body))
+	for name, values := range response.header {
+		size += int64(len(name))
+		for _, v := range values {
+			size += int64(len(v))
+		}
+	}
+	return size
+})
+
+// responseCacheKey identifies a request by method, escaped path, query and optionally user. Query parameters are sorted by Encode.
+// The parts are separated by newlines, which cannot appear in an escaped path or an encoded query,
+// so an encoded "?" or space in the path cannot make a request share the key of another one.
+// It reports false if perUser is set but the request has no authenticated user, as such requests must not share a cache slot.
+func responseCacheKey(r *http.Request, perUser bool) (string, bool) {
+	var key strings.Builder
+	key.WriteString(responseCacheKeyPrefix)
+	key.WriteString(r.Method)
+	key.WriteString("\n")
+	key.WriteString(r.URL.EscapedPath())
+	if query := r.URL.Query().Encode(); query != "" {
+		key.WriteString("?")
+		key.WriteString(query)
+	}
+	if perUser {
+		userCtx, _ := r.Context().Value(goodsContext.ContextKey).(goodsContext.UserContext)
+		if userCtx.UserID == "" {
+			return "", false
+		}
+		key.WriteString("\nuser:")
+		key.WriteString(strconv.Quote(userCtx.UserID))
+	}
+	return key.String(), true
+}
+
+// variantKey extends the key of a request with the values of the request headers its response varies by.
+// It never equals the key itself, which holds the cachedVariants.
+func variantKey(key string, r *http.Request, headers []string) string {
+	var variant strings.Builder
+	variant.WriteString(key)
+	variant.WriteString("\nvariant")
+	for _, header := range headers {
+		variant.WriteString("\n")
+		variant.WriteString(header)
+		variant.WriteString(":")
+		variant.WriteString(strings.Join(r.Header.Values(header), ","))
+	}
+	return variant.String()
+}
+
+func lookupResponse(c *cache.Cache, key string, r *http.Request) (*cachedResponse, bool) {
+	value, found := c.Get(key)
+	if !found {
+		return nil, false
+	}
+	variants, ok := value.(cachedVariants)
+	if !ok {
+		return nil, false
+	}
+	value, found = c.Get(variantKey(key, r, variants.headers))
+	if !found {
+		return nil, false
+	}
+	response, ok := value.(*cachedResponse)
+	return response, ok
+}
+
+func storeResponse(c *cache.Cache, key string, r *http.Request, recorder *responseRecorder, ttl time.Duration) {
+	headers := varyHeaders(recorder.header)
+	c.SetExpiredAfterTimePeriod(key, cachedVariants{headers: headers}, ttl)
+	c.SetExpiredAfterTimePeriod(variantKey(key, r, headers), &cachedResponse{
+		status: recorder.status,
+		header: recorder.header,
+		body:   recorder.body.Bytes(),
+		stored: c.Clock().Now(),
+	}, ttl)
+}
+
+// acceptsCached reports whether the Cache-Control directives of the request allow serving the cached response at now
+func acceptsCached(directives map[string]string, response *cachedResponse, now time.Time) bool {
+	if _, noCache := directives["no-cache"]; noCache {
+		return false
+	}
+	if maxAge, ok := directives["max-age"]; ok {
+		seconds, err := strconv.Atoi(maxAge)
+		if err != nil || now.Sub(response.stored) > time.Duration(seconds)*time.Second {
+			return false
+		}
+	}
+	return true
+}
+
+func writeCachedResponse(w http.ResponseWriter, r *http.Request, response *cachedResponse, now time.Time) {
+	header := w.Header()
+	for name, values := range response.header {
+		header[name] = append([]string(nil), values...)
+	}
+	header.Set("Age", strconv.Itoa(int(now.Sub(response.stored).Seconds())))
+	header.Set("X-Cache", "HIT")
+	w.WriteHeader(response.status)
+	if r.Method != http.MethodHead {
+		w.Write(response.body)
+	}
+}
+
+// responseTTL returns how long the recorded response may be cached, or 0 if it must not be cached
+func responseTTL(recorder *responseRecorder, r *http.Request, config ResponseCacheConfig) time.Duration {
+	if !cacheableStatus(recorder.status) || recorder.header.Get("Set-Cookie") != "" {
+		return 0
+	}
+	for _, header := range recorder.header.Values("Vary") {
+		if strings.TrimSpace(header) == "*" {
+			return 0
+		}
+	}
+
+	directives := parseCacheControl(recorder.header.Get("Cache-Control"))
+	if _, noStore := directives["no-store"]; noStore {
+		return 0
+	}
+	if _, noCache := directives["no-cache"]; noCache {
+		return 0
+	}
+	if _, private := directives["private"]; private && !config.PerUser {
+		return 0
+	}
+	if r.Header.Get("Authorization") != "" && !config.PerUser {
+		// a shared cache only reuses responses to authenticated requests which are explicitly shareable
+		_, public := directives["public"]
+		_, shared := directives["s-maxage"]
+		if !public && !shared {
+			return 0
+		}
+	}
+
+	for _, directive := range []string{"s-maxage", "max-age"} {
+		if value, ok := directives[directive]; ok {
+			seconds, err := strconv.Atoi(value)
+			if err != nil {
+				return 0
+			}
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return routeTTL(r.URL.Path, config)
+}
+
+// routeTTL returns the TTL of the rule with the longest prefix matching path, or the default TTL
+func routeTTL(path string, config ResponseCacheConfig) time.Duration {
+	ttl, longest := config.TTL, -1
+	for _, rule := range config.Rules {
+		if strings.HasPrefix(path, rule.Prefix) && len(rule.Prefix) > longest {
+			ttl, longest = rule.TTL, len(rule.Prefix)
+		}
+	}
+	return ttl
+}
+
+// cacheableStatus reports whether responses with the status code can be cached without explicit freshness information
+func cacheableStatus(status int) bool {
+	switch status {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent,
+		http.StatusMovedPermanently, http.StatusNotFound, http.StatusGone:
+		return true
+	}
+	return false
+}
+
+// varyHeaders returns the canonical names of the request headers listed in the Vary header
+func varyHeaders(header http.Header) []string {
+	var headers []string
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				headers = append(headers, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return headers
+}
+
+// parseCacheControl parses a Cache-Control header into lower-case directives and their unquoted values
+func parseCacheControl(header string) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(header, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name == "" {
+			continue
+		}
+		directives[strings.ToLower(name)] = strings.Trim(value, `"`)
+	}
+	return directives
+}
+
+// responseRecorder writes the response through to the client while keeping a copy of it for the cache
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	header      http.Header
+	body        bytes.Buffer
+	wroteHeader bool
+	maxBody     int64                                 // largest body kept for the cache
+	ttlOf       func(*responseRecorder) time.Duration // tells from the status and headers how long the response may be cached
+	ttl         time.Duration                         // 0 if the response is not cached, in which case its body is not kept
+}
+
+// WriteHeader captures the status code and a copy of the headers, and decides from them whether the body is kept for the cache
+func (rec *responseRecorder) WriteHeader(code int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.wroteHeader = true
+	rec.status = code
+	rec.header = rec.ResponseWriter.Header().Clone()
+	rec.ttl = rec.ttlOf(rec)
+	if length, err := strconv.ParseInt(rec.header.Get("Content-Length"), 10, 64); err == nil && length > rec.maxBody {
+		rec.ttl = 0
+	}
+	rec.ResponseWriter.Header().Set("X-Cache", "MISS")
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+// Write captures the body of a cacheable response until it grows larger than maxBody, after which the response is not cached
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if rec.ttl > 0 {
+		if int64(rec.body.Len())+int64(len(b)) > rec.maxBody {
+			rec.ttl = 0
+			rec.body = bytes.Buffer{}
+		} else {
+			rec.body.Write(b)
+		}
+	}
+	return rec.ResponseWriter.Write(b)
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/cache"
+	"go.uber.org/zap"
+)
+
+func BenchmarkResponseCacheHit(b *testing.B) {
+	var calls atomic.Int32
+	handler := ResponseCache(cache.NewCache(zap.NewNop()), ResponseCacheConfig{TTL: time.Minute})(
+		countingHandler(&calls, nil, http.StatusOK),
+	)
+	r := httptest.NewRequest(http.MethodGet, "/prices?from=1&to=2", nil)
+
+	for b.Loop() {
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+}
+
+func BenchmarkResponseCacheMiss(b *testing.B) {
+	var calls atomic.Int32
+	handler := ResponseCache(cache.NewCache(zap.NewNop()), ResponseCacheConfig{})(
+		countingHandler(&calls, nil, http.StatusOK),
+	)
+	r := httptest.NewRequest(http.MethodGet, "/prices?from=1&to=2", nil)
+
+	for b.Loop() {
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+}
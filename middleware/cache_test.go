@@ -0,0 +1,415 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AnhCaooo/go-goods/cache"
+	"github.com/AnhCaooo/go-goods/clock"
+	goodsContext "github.com/AnhCaooo/go-goods/context"
+	"go.uber.org/zap"
+)
+
+// countingHandler answers with the number of requests it has served, setting the given response headers
+func countingHandler(calls *atomic.Int32, header http.Header, status int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		for name, values := range header {
+			w.Header()[name] = values
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprintf(w, `{"call":%d}`, n)
+	})
+}
+
+func serve(handler http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, r)
+	return recorder
+}
+
+func TestResponseCache(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		status         int
+		responseHeader http.Header
+		firstHeader    http.Header
+		secondHeader   http.Header
+		firstTarget    string
+		secondTarget   string
+		expectedCalls  int32
+	}{
+		{
+			name:          "Identical GET is served from cache",
+			expectedCalls: 1,
+		},
+		{
+			name:          "HEAD is served from cache",
+			method:        http.MethodHead,
+			expectedCalls: 1,
+		},
+		{
+			name:          "POST is not cached",
+			method:        http.MethodPost,
+			expectedCalls: 2,
+		},
+		{
+			name:          "Query order does not matter",
+			secondTarget:  "/prices?to=2&from=1",
+			expectedCalls: 1,
+		},
+		{
+			name:          "Encoded question mark in the path is not the query",
+			status:        http.StatusNotFound,
+			firstTarget:   "/prices%3Ffrom=1&to=2",
+			expectedCalls: 2,
+		},
+		{
+			name:          "Encoded space in the path is cached separately",
+			firstTarget:   "/prices%20variant",
+			secondTarget:  "/prices",
+			expectedCalls: 2,
+		},
+		{
+			name:          "Different query is cached separately",
+			secondTarget:  "/prices?from=1&to=3",
+			expectedCalls: 2,
+		},
+		{
+			name:          "Server error is not cached",
+			status:        http.StatusInternalServerError,
+			expectedCalls: 2,
+		},
+		{
+			name:           "Response with no-store is not cached",
+			responseHeader: http.Header{"Cache-Control": {"no-store"}},
+			expectedCalls:  2,
+		},
+		{
+			name:           "Private response is not cached",
+			responseHeader: http.Header{"Cache-Control": {"private, max-age=60"}},
+			expectedCalls:  2,
+		},
+		{
+			name:           "Response with cookie is not cached",
+			responseHeader: http.Header{"Set-Cookie": {"session=1"}},
+			expectedCalls:  2,
+		},
+		{
+			name:           "Response with max-age=0 is not cached",
+			responseHeader: http.Header{"Cache-Control": {"max-age=0"}},
+			expectedCalls:  2,
+		},
+		{
+			name:          "Request with no-cache skips the cached response",
+			secondHeader:  http.Header{"Cache-Control": {"no-cache"}},
+			expectedCalls: 2,
+		},
+		{
+			name:          "Request with no-store bypasses the cache",
+			firstHeader:   http.Header{"Cache-Control": {"no-store"}},
+			expectedCalls: 2,
+		},
+		{
+			name:          "Request with max-age accepts a fresh response",
+			secondHeader:  http.Header{"Cache-Control": {"max-age=60"}},
+			expectedCalls: 1,
+		},
+		{
+			name:          "Authenticated request is not cached in a shared cache",
+			firstHeader:   http.Header{"Authorization": {"Bearer token"}},
+			secondHeader:  http.Header{"Authorization": {"Bearer token"}},
+			expectedCalls: 2,
+		},
+		{
+			name:           "Public response to an authenticated request is cached",
+			responseHeader: http.Header{"Cache-Control": {"public"}},
+			firstHeader:    http.Header{"Authorization": {"Bearer token"}},
+			secondHeader:   http.Header{"Authorization": {"Bearer token"}},
+			expectedCalls:  1,
+		},
+		{
+			name:           "Same Vary header value is served from cache",
+			responseHeader: http.Header{"Vary": {"Accept-Language"}},
+			firstHeader:    http.Header{"Accept-Language": {"fi"}},
+			secondHeader:   http.Header{"Accept-Language": {"fi"}},
+			expectedCalls:  1,
+		},
+		{
+			name:           "Different Vary header value is cached separately",
+			responseHeader: http.Header{"Vary": {"Accept-Language"}},
+			firstHeader:    http.Header{"Accept-Language": {"fi"}},
+			secondHeader:   http.Header{"Accept-Language": {"en"}},
+			expectedCalls:  2,
+		},
+		{
+			name:           "Vary * is not cached",
+			responseHeader: http.Header{"Vary": {"*"}},
+			expectedCalls:  2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			method := test.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			status := test.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			firstTarget := test.firstTarget
+			if firstTarget == "" {
+				firstTarget = "/prices?from=1&to=2"
+			}
+			secondTarget := test.secondTarget
+			if secondTarget == "" {
+				secondTarget = "/prices?from=1&to=2"
+			}
+
+			var calls atomic.Int32
+			handler := ResponseCache(cache.NewCache(zap.NewNop()), ResponseCacheConfig{TTL: time.Minute})(
+				countingHandler(&calls, test.responseHeader, status),
+			)
+
+			first := httptest.NewRequest(method, firstTarget, nil)
+			first.Header = test.firstHeader.Clone()
+			if first.Header == nil {
+				first.Header = http.Header{}
+			}
+			serve(handler, first)
+
+			second := httptest.NewRequest(method, secondTarget, nil)
+			second.Header = test.secondHeader.Clone()
+			if second.Header == nil {
+				second.Header = http.Header{}
+			}
+			response := serve(handler, second)
+
+			if n := calls.Load(); n != test.expectedCalls {
+				t.Errorf("Expected %d handler calls, got %d", test.expectedCalls, n)
+			}
+			if response.Code != status {
+				t.Errorf("Expected status %d, got %d", status, response.Code)
+			}
+		})
+	}
+}
+
+func TestResponseCacheReplaysResponse(t *testing.T) {
+	var calls atomic.Int32
+	header := http.Header{"X-Request-Id": {"abc"}}
+	handler := ResponseCache(cache.NewCache(zap.NewNop()), ResponseCacheConfig{TTL: time.Minute})(
+		countingHandler(&calls, header, http.StatusNotFound),
+	)
+
+	miss := serve(handler, httptest.NewRequest(http.MethodGet, "/prices", nil))
+	hit := serve(handler, httptest.NewRequest(http.MethodGet, "/prices", nil))
+
+	if miss.Header().Get("X-Cache") != "MISS" || hit.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("Expected X-Cache MISS then HIT, got %q then %q", miss.Header().Get("X-Cache"), hit.Header().Get("X-Cache"))
+	}
+	if hit.Code != http.StatusNotFound {
+		t.Errorf("Expected cached status %d, got %d", http.StatusNotFound, hit.Code)
+	}
+	if hit.Body.String() != miss.Body.String() {
+		t.Errorf("Expected cached body %q, got %q", miss.Body.String(), hit.Body.String())
+	}
+	for _, name := range []string{"Content-Type", "X-Request-Id"} {
+		if hit.Header().Get(name) != miss.Header().Get(name) {
+			t.Errorf("Expected cached header %s=%q, got %q", name, miss.Header().Get(name), hit.Header().Get(name))
+		}
+	}
+	if hit.Header().Get("Age") == "" {
+		t.Error("Cached response should have an Age header")
+	}
+}
+
+func TestResponseCacheFreshness(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	var calls atomic.Int32
+	handler := ResponseCache(cache.NewCache(zap.NewNop(), cache.WithClock(clk)), ResponseCacheConfig{TTL: 10 * time.Minute})(
+		countingHandler(&calls, nil, http.StatusOK),
+	)
+	serve(handler, httptest.NewRequest(http.MethodGet, "/prices", nil))
+	clk.Advance(2 * time.Minute)
+
+	hit := serve(handler, httptest.NewRequest(http.MethodGet, "/prices", nil))
+	if age := hit.Header().Get("Age"); age != "120" {
+		t.Errorf("Expected Age 120, got %q", age)
+	}
+
+	fresh := httptest.NewRequest(http.MethodGet, "/prices", nil)
+	fresh.Header.Set("Cache-Control", "max-age=180")
+	serve(handler, fresh)
+	if n := calls.Load(); n != 1 {
+		t.Errorf("Expected a response fresh enough for max-age to be served from cache, got %d handler calls", n)
+	}
+
+	stale := httptest.NewRequest(http.MethodGet, "/prices", nil)
+	stale.Header.Set("Cache-Control", "max-age=60")
+	if response := serve(handler, stale); response.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("Expected a response older than max-age to be refused, got X-Cache %q", response.Header().Get("X-Cache"))
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("Expected 2 handler calls, got %d", n)
+	}
+}
+
+func TestResponseCacheMaxBodySize(t *testing.T) {
+	tests := []struct {
+		name          string
+		chunks        int
+		contentLength bool
+		expectedCalls int32
+	}{
+		{name: "Body within the limit is cached", chunks: 2, expectedCalls: 1},
+		{name: "Body growing over the limit is not cached", chunks: 5, expectedCalls: 2},
+		{name: "Content-Length over the limit is not cached", chunks: 5, contentLength: true, expectedCalls: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var calls atomic.Int32
+			chunk := strings.Repeat("x", 10)
+			handler := ResponseCache(cache.NewCache(zap.NewNop()), ResponseCacheConfig{TTL: time.Minute, MaxBodySize: 25})(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					calls.Add(1)
+					if test.contentLength {
+						w.Header().Set("Content-Length", strconv.Itoa(test.chunks*len(chunk)))
+					}
+					for i := 0; i < test.chunks; i++ {
+						io.WriteString(w, chunk)
+					}
+				}),
+			)
+
+			serve(handler, httptest.NewRequest(http.MethodGet, "/download", nil))
+			response := serve(handler, httptest.NewRequest(http.MethodGet, "/download", nil))
+
+			if n := calls.Load(); n != test.expectedCalls {
+				t.Errorf("Expected %d handler calls, got %d", test.expectedCalls, n)
+			}
+			if expected := strings.Repeat(chunk, test.chunks); response.Body.String() != expected {
+				t.Errorf("Expected the complete body %q, got %q", expected, response.Body.String())
+			}
+		})
+	}
+}
+
+func TestResponseCacheTTL(t *testing.T) {
+	config := ResponseCacheConfig{
+		TTL: time.Minute,
+		Rules: []CacheRule{
+			{Prefix: "/v1/prices", TTL: 10 * time.Minute},
+			{Prefix: "/v1/prices/live", TTL: 0},
+		},
+	}
+	tests := []struct {
+		name           string
+		path           string
+		responseHeader http.Header
+		expectedTTL    time.Duration
+	}{
+		{name: "Default TTL", path: "/v1/settings", expectedTTL: time.Minute},
+		{name: "Rule TTL", path: "/v1/prices/today", expectedTTL: 10 * time.Minute},
+		{name: "Longest rule wins", path: "/v1/prices/live", expectedTTL: 0},
+		{
+			name:           "Response max-age overrides the rule",
+			path:           "/v1/prices/today",
+			responseHeader: http.Header{"Cache-Control": {"max-age=30"}},
+			expectedTTL:    30 * time.Second,
+		},
+		{
+			name:           "Response s-maxage overrides max-age",
+			path:           "/v1/prices/today",
+			responseHeader: http.Header{"Cache-Control": {`max-age=30, s-maxage="120"`}},
+			expectedTTL:    2 * time.Minute,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := &responseRecorder{status: http.StatusOK, header: test.responseHeader}
+			if ttl := responseTTL(recorder, httptest.NewRequest(http.MethodGet, test.path, nil), config); ttl != test.expectedTTL {
+				t.Errorf("Expected TTL %v, got %v", test.expectedTTL, ttl)
+			}
+		})
+	}
+}
+
+func TestResponseCachePerUser(t *testing.T) {
+	var calls atomic.Int32
+	handler := ResponseCache(cache.NewCache(zap.NewNop()), ResponseCacheConfig{TTL: time.Minute, PerUser: true})(
+		countingHandler(&calls, http.Header{"Cache-Control": {"private"}}, http.StatusOK),
+	)
+	requestAs := func(userID string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/settings", nil)
+		r.Header.Set("Authorization", "Bearer token")
+		ctx := context.WithValue(r.Context(), goodsContext.ContextKey, goodsContext.UserContext{UserID: userID})
+		return r.WithContext(ctx)
+	}
+
+	alice := serve(handler, requestAs("alice"))
+	bob := serve(handler, requestAs("bob"))
+	aliceAgain := serve(handler, requestAs("alice"))
+
+	if n := calls.Load(); n != 2 {
+		t.Errorf("Expected 2 handler calls, got %d", n)
+	}
+	if bob.Body.String() == alice.Body.String() {
+		t.Error("A user should not get the cached response of another user")
+	}
+	if aliceAgain.Body.String() != alice.Body.String() {
+		t.Errorf("Expected cached response %q, got %q", alice.Body.String(), aliceAgain.Body.String())
+	}
+}
+
+func TestResponseCachePerUserWithoutUser(t *testing.T) {
+	var calls atomic.Int32
+	handler := ResponseCache(cache.NewCache(zap.NewNop()), ResponseCacheConfig{TTL: time.Minute, PerUser: true})(
+		countingHandler(&calls, http.Header{"Cache-Control": {"private"}}, http.StatusOK),
+	)
+	tests := []struct {
+		name    string
+		request func() *http.Request
+	}{
+		{
+			name:    "No user context",
+			request: func() *http.Request { return httptest.NewRequest(http.MethodGet, "/settings", nil) },
+		},
+		{
+			name: "Empty user ID",
+			request: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/settings", nil)
+				return r.WithContext(context.WithValue(r.Context(), goodsContext.ContextKey, goodsContext.UserContext{}))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls.Store(0)
+			first := serve(handler, test.request())
+			second := serve(handler, test.request())
+
+			if n := calls.Load(); n != 2 {
+				t.Errorf("Expected every request without a user to reach the handler, got %d calls", n)
+			}
+			if second.Body.String() == first.Body.String() || second.Header().Get("X-Cache") == "HIT" {
+				t.Error("A request without a user should not get a cached response")
+			}
+		})
+	}
+}
@@ -56,9 +56,11 @@ func (rec *StatusRecorder) WriteHeader(code int) {
 }
 
 // PrometheusHandler returns a handler for Prometheus metrics
-// This handler serves the metrics at the /metrics endpoint
-func PrometheusHandler() *http.Handler {
+// This handler serves the metrics at the /metrics endpoint.
+// Additional collectors, e.g. cache.NewCollector, are registered next to the default metrics
+func PrometheusHandler(collectors ...prometheus.Collector) *http.Handler {
 	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors...)
 	registry.MustRegister(HttpRequestCounter)
 	registry.MustRegister(Gauge)
 	registry.MustRegister(ActiveRequestsGauge)